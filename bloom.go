@@ -0,0 +1,51 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+func (b *BPFMap) checkBloomFilter() error {
+	if t := b.Type(); t != MapTypeBloomFilter {
+		return fmt.Errorf("map %s is not a bloom filter: %s: %w", b.name, t, syscall.EINVAL)
+	}
+	return nil
+}
+
+// BloomAdd adds value to a bloom filter. Values can not be removed.
+func (b *BPFMap) BloomAdd(value unsafe.Pointer) error {
+	if err := b.checkBloomFilter(); err != nil {
+		return err
+	}
+
+	errC := C.bpf_map_update_elem(b.fd, nil, value, C.BPF_ANY)
+	if errC != 0 {
+		return fmt.Errorf("failed to add to bloom filter %s: %w", b.name, syscall.Errno(-errC))
+	}
+	return nil
+}
+
+// BloomContains tells whether value may have been added to a bloom filter.
+// It has false positives, at a rate depending on the size of the filter and
+// its number of hash functions (see SetMapExtra), but no false negatives.
+func (b *BPFMap) BloomContains(value unsafe.Pointer) (bool, error) {
+	if err := b.checkBloomFilter(); err != nil {
+		return false, err
+	}
+
+	errC := C.bpf_map_lookup_elem(b.fd, nil, value)
+	if errC != 0 {
+		errno := syscall.Errno(-errC)
+		if errno == syscall.ENOENT {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lookup bloom filter %s: %w", b.name, errno)
+	}
+	return true, nil
+}
@@ -0,0 +1,663 @@
+// Package btf parses BPF Type Format (BTF) data in Go, to inspect kernel
+// types without libbpf: struct layouts, enum values, function prototypes
+// and whether a field exists on the running kernel.
+//
+//	spec, err := btf.LoadKernelSpec()
+//	...
+//	task, err := spec.FindStruct("task_struct")
+//	...
+//	pid, err := task.Member("pid")
+package btf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when a type, member or value does not exist
+var ErrNotFound = errors.New("not found")
+
+const (
+	btfMagic     = 0xeb9f
+	btfHeaderLen = 24
+
+	// KernelBTFDir is where the kernel exposes the BTF of vmlinux and of
+	// its modules
+	KernelBTFDir = "/sys/kernel/btf"
+)
+
+type btfHeader struct {
+	Magic   uint16
+	Version uint8
+	Flags   uint8
+	HdrLen  uint32
+	TypeOff uint32
+	TypeLen uint32
+	StrOff  uint32
+	StrLen  uint32
+}
+
+// Spec holds the types of a BTF blob. The Spec of a kernel module (split
+// BTF) also holds the vmlinux types it builds upon.
+type Spec struct {
+	base    *Spec
+	types   []Type // by ID, starting with the base types
+	strings []byte
+	strOff  uint32 // size of the strings of the base
+	byName  map[string][]Type
+}
+
+// LoadKernelSpec loads the BTF of the running kernel
+func LoadKernelSpec() (*Spec, error) {
+	return LoadSpec(filepath.Join(KernelBTFDir, "vmlinux"))
+}
+
+// LoadKernelModuleSpec loads the BTF of a kernel module, on top of the BTF
+// of the running kernel
+func LoadKernelModuleSpec(module string) (*Spec, error) {
+	base, err := LoadKernelSpec()
+	if err != nil {
+		return nil, err
+	}
+	return LoadSplitSpec(filepath.Join(KernelBTFDir, module), base)
+}
+
+// KernelModules lists the kernel modules exposing their BTF
+func KernelModules() ([]string, error) {
+	entries, err := os.ReadDir(KernelBTFDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	for _, entry := range entries {
+		if entry.Name() != "vmlinux" {
+			modules = append(modules, entry.Name())
+		}
+	}
+	return modules, nil
+}
+
+// LoadSpec loads BTF from a file: either raw BTF, such as
+// /sys/kernel/btf/vmlinux or BTFHub files, or an ELF file with a .BTF
+// section.
+func LoadSpec(path string) (*Spec, error) {
+	return LoadSplitSpec(path, nil)
+}
+
+// LoadSplitSpec loads split BTF from a file, the types of which are
+// numbered after the ones of base
+func LoadSplitSpec(path string, base *Spec) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		data, err = elfBTF(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spec, err := ParseSplit(data, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BTF %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+func elfBTF(path string) ([]byte, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sec := f.Section(".BTF")
+	if sec == nil {
+		return nil, fmt.Errorf("%s has no .BTF section: %w", path, ErrNotFound)
+	}
+	return sec.Data()
+}
+
+// Parse parses raw BTF data
+func Parse(data []byte) (*Spec, error) {
+	return ParseSplit(data, nil)
+}
+
+// ParseSplit parses raw split BTF data on top of base. A nil base parses
+// standalone BTF.
+func ParseSplit(data []byte, base *Spec) (*Spec, error) {
+	if len(data) < btfHeaderLen {
+		return nil, errors.New("truncated header")
+	}
+
+	var bo binary.ByteOrder = binary.LittleEndian
+	if bo.Uint16(data) != btfMagic {
+		bo = binary.BigEndian
+		if bo.Uint16(data) != btfMagic {
+			return nil, errors.New("invalid magic")
+		}
+	}
+
+	var hdr btfHeader
+	if err := binary.Read(bytes.NewReader(data), bo, &hdr); err != nil {
+		return nil, err
+	}
+	if hdr.HdrLen < btfHeaderLen {
+		return nil, fmt.Errorf("invalid header length %d", hdr.HdrLen)
+	}
+
+	typeData, err := section(data, hdr.HdrLen, hdr.TypeOff, hdr.TypeLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type section: %w", err)
+	}
+	strData, err := section(data, hdr.HdrLen, hdr.StrOff, hdr.StrLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid string section: %w", err)
+	}
+
+	s := &Spec{
+		base:    base,
+		strings: append([]byte(nil), strData...),
+		byName:  make(map[string][]Type),
+	}
+	if base == nil {
+		s.types = []Type{&Void{}}
+	} else {
+		s.types = base.types[:len(base.types):len(base.types)]
+		s.strOff = base.strOff + uint32(len(base.strings))
+	}
+
+	if err = s.parseTypes(typeData, bo); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func section(data []byte, hdrLen, off, length uint32) ([]byte, error) {
+	start := uint64(hdrLen) + uint64(off)
+	end := start + uint64(length)
+	if end > uint64(len(data)) {
+		return nil, errors.New("out of bounds")
+	}
+	return data[start:end], nil
+}
+
+// str returns the string at offset off of the string section
+func (s *Spec) str(off uint32) (string, error) {
+	if off < s.strOff {
+		return s.base.str(off)
+	}
+	off -= s.strOff
+	if off >= uint32(len(s.strings)) {
+		return "", fmt.Errorf("invalid string offset %d", off+s.strOff)
+	}
+	end := bytes.IndexByte(s.strings[off:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at offset %d", off+s.strOff)
+	}
+	return string(s.strings[off : off+uint32(end)]), nil
+}
+
+// TypeByID returns the type id
+func (s *Spec) TypeByID(id TypeID) (Type, error) {
+	if int(id) >= len(s.types) {
+		return nil, fmt.Errorf("type %d: %w", id, ErrNotFound)
+	}
+	return s.types[id], nil
+}
+
+// NumTypes returns the number of types, void and base types included.
+// Valid type IDs are below it.
+func (s *Spec) NumTypes() int {
+	return len(s.types)
+}
+
+// TypesByName returns all the types with the given name
+func (s *Spec) TypesByName(name string) []Type {
+	var types []Type
+	if s.base != nil {
+		types = s.base.TypesByName(name)
+	}
+	return append(types, s.byName[name]...)
+}
+
+// Find returns the first type with the given name and kind
+func (s *Spec) Find(name string, kind Kind) (Type, error) {
+	for _, t := range s.TypesByName(name) {
+		if t.Kind() == kind {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: %w", kind, name, ErrNotFound)
+}
+
+// FindStruct returns the struct with the given name
+func (s *Spec) FindStruct(name string) (*Struct, error) {
+	t, err := s.Find(name, KindStruct)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*Struct), nil
+}
+
+// FindUnion returns the union with the given name
+func (s *Spec) FindUnion(name string) (*Union, error) {
+	t, err := s.Find(name, KindUnion)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*Union), nil
+}
+
+// FindEnum returns the enum (32 or 64-bit) with the given name
+func (s *Spec) FindEnum(name string) (*Enum, error) {
+	for _, t := range s.TypesByName(name) {
+		if e, ok := t.(*Enum); ok {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("enum %s: %w", name, ErrNotFound)
+}
+
+// FindTypedef returns the typedef with the given name
+func (s *Spec) FindTypedef(name string) (*Typedef, error) {
+	t, err := s.Find(name, KindTypedef)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*Typedef), nil
+}
+
+// FindFunc returns the function with the given name
+func (s *Spec) FindFunc(name string) (*Func, error) {
+	t, err := s.Find(name, KindFunc)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*Func), nil
+}
+
+// FindEnumValue looks an enumerator up in all enums, named or anonymous
+func (s *Spec) FindEnumValue(name string) (int64, error) {
+	for _, t := range s.types {
+		if e, ok := t.(*Enum); ok {
+			if v, err := e.Value(name); err == nil {
+				return v, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("enum value %s: %w", name, ErrNotFound)
+}
+
+// FindMember resolves a dot separated member path, e.g. "se.vruntime",
+// from the struct, union or typedef typeName. The offset of the returned
+// member is relative to typeName.
+func (s *Spec) FindMember(typeName, path string) (*Member, error) {
+	var t Type
+	for _, cand := range s.TypesByName(typeName) {
+		if k := UnderlyingType(cand).Kind(); k == KindStruct || k == KindUnion {
+			t = cand
+			break
+		}
+	}
+	if t == nil {
+		return nil, fmt.Errorf("struct or union %s: %w", typeName, ErrNotFound)
+	}
+
+	var member *Member
+	var offset uint32
+	for _, name := range strings.Split(path, ".") {
+		var err error
+		switch v := UnderlyingType(t).(type) {
+		case *Struct:
+			member, err = v.Member(name)
+		case *Union:
+			member, err = v.Member(name)
+		default:
+			err = fmt.Errorf("%s %s has no member %s: %w", t.Kind(), t.Name(), name, ErrNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset += member.Offset
+		t = member.Type
+	}
+
+	found := *member
+	found.Offset = offset
+	return &found, nil
+}
+
+// FieldExists tells whether typeName has the member path (see FindMember)
+func (s *Spec) FieldExists(typeName, path string) bool {
+	_, err := s.FindMember(typeName, path)
+	return err == nil
+}
+
+// rawType is a type as laid out in the type section, with its references
+// to other types not resolved yet
+type rawType struct {
+	nameOff  uint32
+	info     uint32
+	sizeType uint32
+	data     []byte // kind specific data following struct btf_type
+}
+
+func (r *rawType) kind() Kind {
+	return Kind((r.info >> 24) & 0x1f)
+}
+
+func (r *rawType) vlen() int {
+	return int(r.info & 0xffff)
+}
+
+func (r *rawType) kflag() bool {
+	return r.info&(1<<31) != 0
+}
+
+// kindDataSize returns the size of the data following struct btf_type
+func kindDataSize(kind Kind, vlen int) (int, error) {
+	switch kind {
+	case KindPointer, KindFwd, KindTypedef, KindVolatile, KindConst,
+		KindRestrict, KindFunc, KindFloat, KindTypeTag:
+		return 0, nil
+	case KindInt, KindVar, KindDeclTag:
+		return 4, nil
+	case KindArray:
+		return 12, nil
+	case KindStruct, KindUnion, KindDatasec, KindEnum64:
+		return 12 * vlen, nil
+	case KindEnum, KindFuncProto:
+		return 8 * vlen, nil
+	}
+	return 0, fmt.Errorf("unknown kind %d", kind)
+}
+
+func (s *Spec) parseTypes(data []byte, bo binary.ByteOrder) error {
+	var raws []rawType
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return errors.New("truncated type")
+		}
+		raw := rawType{
+			nameOff:  bo.Uint32(data),
+			info:     bo.Uint32(data[4:]),
+			sizeType: bo.Uint32(data[8:]),
+		}
+		size, err := kindDataSize(raw.kind(), raw.vlen())
+		if err != nil {
+			return fmt.Errorf("type %d: %w", len(s.types)+len(raws), err)
+		}
+		if len(data) < 12+size {
+			return errors.New("truncated type")
+		}
+		raw.data = data[12 : 12+size]
+		raws = append(raws, raw)
+		data = data[12+size:]
+	}
+
+	// create all the types first, so that references can be resolved
+	firstID := len(s.types)
+	for i, raw := range raws {
+		name, err := s.str(raw.nameOff)
+		if err != nil {
+			return err
+		}
+		base := typeBase{id: TypeID(firstID + i), name: name}
+
+		var t Type
+		switch raw.kind() {
+		case KindInt:
+			enc := bo.Uint32(raw.data)
+			t = &Int{
+				typeBase: base,
+				Size:     raw.sizeType,
+				Encoding: IntEncoding((enc >> 24) & 0x0f),
+				Offset:   (enc >> 16) & 0xff,
+				Bits:     enc & 0xff,
+			}
+		case KindPointer:
+			t = &Pointer{typeBase: base}
+		case KindArray:
+			t = &Array{typeBase: base, Nelems: bo.Uint32(raw.data[8:])}
+		case KindStruct:
+			t = &Struct{typeBase: base, Size: raw.sizeType}
+		case KindUnion:
+			t = &Union{typeBase: base, Size: raw.sizeType}
+		case KindEnum, KindEnum64:
+			t = &Enum{typeBase: base, Size: raw.sizeType, Signed: raw.kflag(), is64: raw.kind() == KindEnum64}
+		case KindFwd:
+			fwd := &Fwd{typeBase: base}
+			if raw.kflag() {
+				fwd.FwdKind = FwdUnion
+			}
+			t = fwd
+		case KindTypedef:
+			t = &Typedef{typeBase: base}
+		case KindVolatile:
+			t = &Volatile{typeBase: base}
+		case KindConst:
+			t = &Const{typeBase: base}
+		case KindRestrict:
+			t = &Restrict{typeBase: base}
+		case KindFunc:
+			t = &Func{typeBase: base, Linkage: Linkage(raw.vlen())}
+		case KindFuncProto:
+			t = &FuncProto{typeBase: base}
+		case KindVar:
+			t = &Var{typeBase: base, Linkage: Linkage(bo.Uint32(raw.data))}
+		case KindDatasec:
+			t = &Datasec{typeBase: base, Size: raw.sizeType}
+		case KindFloat:
+			t = &Float{typeBase: base, Size: raw.sizeType}
+		case KindDeclTag:
+			t = &DeclTag{typeBase: base, ComponentIdx: int32(bo.Uint32(raw.data))}
+		case KindTypeTag:
+			t = &TypeTag{typeBase: base}
+		}
+
+		s.types = append(s.types, t)
+		if name != "" {
+			s.byName[name] = append(s.byName[name], t)
+		}
+	}
+
+	for i, raw := range raws {
+		if err := s.resolve(s.types[firstID+i], &raw, bo); err != nil {
+			return fmt.Errorf("type %d: %w", firstID+i, err)
+		}
+	}
+
+	return s.checkCycles(firstID)
+}
+
+// checkCycles rejects types made of themselves, e.g. a typedef of a const
+// of the typedef, or a struct with a member of its own type: following
+// them, as UnderlyingType and Sizeof do, would never end. Types below
+// firstID, of the base spec, were checked when it was parsed.
+func (s *Spec) checkCycles(firstID int) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make([]uint8, len(s.types)-firstID)
+
+	var visit func(t Type) error
+	visit = func(t Type) error {
+		i := int(t.ID()) - firstID
+		if i < 0 || state[i] == done {
+			return nil
+		}
+		if state[i] == visiting {
+			return fmt.Errorf("type %d: %s %s refers to itself", t.ID(), t.Kind(), t.Name())
+		}
+		state[i] = visiting
+		for _, e := range embeddedTypes(t) {
+			if e == nil {
+				continue
+			}
+			if err := visit(e); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		return nil
+	}
+
+	for _, t := range s.types[firstID:] {
+		if err := visit(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embeddedTypes returns the types t is made of, as opposed to the types it
+// points to
+func embeddedTypes(t Type) []Type {
+	switch v := t.(type) {
+	case *Typedef:
+		return []Type{v.Type}
+	case *Const:
+		return []Type{v.Type}
+	case *Volatile:
+		return []Type{v.Type}
+	case *Restrict:
+		return []Type{v.Type}
+	case *TypeTag:
+		return []Type{v.Type}
+	case *Var:
+		return []Type{v.Type}
+	case *Array:
+		return []Type{v.Type}
+	case *Struct:
+		return memberTypes(v.Members)
+	case *Union:
+		return memberTypes(v.Members)
+	case *Datasec:
+		types := make([]Type, 0, len(v.Vars))
+		for _, sv := range v.Vars {
+			types = append(types, sv.Var)
+		}
+		return types
+	}
+	return nil
+}
+
+func memberTypes(members []Member) []Type {
+	types := make([]Type, 0, len(members))
+	for _, m := range members {
+		types = append(types, m.Type)
+	}
+	return types
+}
+
+// resolve fills in the references of t to other types
+func (s *Spec) resolve(t Type, raw *rawType, bo binary.ByteOrder) error {
+	var err error
+	ref := func(id uint32) Type {
+		if int(id) >= len(s.types) {
+			if err == nil {
+				err = fmt.Errorf("invalid type ID %d", id)
+			}
+			return nil
+		}
+		return s.types[id]
+	}
+	str := func(off uint32) string {
+		name, strErr := s.str(off)
+		if strErr != nil && err == nil {
+			err = strErr
+		}
+		return name
+	}
+
+	switch v := t.(type) {
+	case *Pointer:
+		v.Target = ref(raw.sizeType)
+	case *Array:
+		v.Type = ref(bo.Uint32(raw.data))
+		v.IndexType = ref(bo.Uint32(raw.data[4:]))
+	case *Struct:
+		v.Members = parseMembers(raw, bo, ref, str)
+	case *Union:
+		v.Members = parseMembers(raw, bo, ref, str)
+	case *Enum:
+		for i := 0; i < raw.vlen(); i++ {
+			var value int64
+			var rec []byte
+			if v.is64 {
+				rec = raw.data[12*i:]
+				value = int64(uint64(bo.Uint32(rec[8:]))<<32 | uint64(bo.Uint32(rec[4:])))
+			} else {
+				rec = raw.data[8*i:]
+				if v.Signed {
+					value = int64(int32(bo.Uint32(rec[4:])))
+				} else {
+					value = int64(bo.Uint32(rec[4:]))
+				}
+			}
+			v.Values = append(v.Values, EnumValue{Name: str(bo.Uint32(rec)), Value: value})
+		}
+	case *Typedef:
+		v.Type = ref(raw.sizeType)
+	case *Volatile:
+		v.Type = ref(raw.sizeType)
+	case *Const:
+		v.Type = ref(raw.sizeType)
+	case *Restrict:
+		v.Type = ref(raw.sizeType)
+	case *TypeTag:
+		v.Type = ref(raw.sizeType)
+	case *DeclTag:
+		v.Type = ref(raw.sizeType)
+	case *Var:
+		v.Type = ref(raw.sizeType)
+	case *Func:
+		proto, ok := ref(raw.sizeType).(*FuncProto)
+		if !ok && err == nil {
+			err = fmt.Errorf("func %s has no prototype", v.name)
+		}
+		v.Proto = proto
+	case *FuncProto:
+		v.Return = ref(raw.sizeType)
+		for i := 0; i < raw.vlen(); i++ {
+			rec := raw.data[8*i:]
+			v.Params = append(v.Params, FuncParam{Name: str(bo.Uint32(rec)), Type: ref(bo.Uint32(rec[4:]))})
+		}
+	case *Datasec:
+		for i := 0; i < raw.vlen(); i++ {
+			rec := raw.data[12*i:]
+			v.Vars = append(v.Vars, VarSecinfo{Var: ref(bo.Uint32(rec)), Offset: bo.Uint32(rec[4:]), Size: bo.Uint32(rec[8:])})
+		}
+	}
+
+	return err
+}
+
+func parseMembers(raw *rawType, bo binary.ByteOrder, ref func(uint32) Type, str func(uint32) string) []Member {
+	members := make([]Member, 0, raw.vlen())
+	for i := 0; i < raw.vlen(); i++ {
+		rec := raw.data[12*i:]
+		m := Member{
+			Name:   str(bo.Uint32(rec)),
+			Type:   ref(bo.Uint32(rec[4:])),
+			Offset: bo.Uint32(rec[8:]),
+		}
+		if raw.kflag() {
+			m.BitfieldSize = m.Offset >> 24
+			m.Offset &= 0xffffff
+		}
+		members = append(members, m)
+	}
+	return members
+}
@@ -0,0 +1,280 @@
+package btf
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestSpec(t *testing.T) *Spec {
+	spec, err := LoadSpec("testdata/vmlinux.btf")
+	require.NoError(t, err)
+	return spec
+}
+
+func TestFindStruct(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	task, err := spec.FindStruct("task_struct")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(64), task.Size)
+	assert.Len(t, task.Members, 8)
+
+	testCases := []struct {
+		member       string
+		offset       uint32
+		bitfieldSize uint32
+		kind         Kind
+	}{
+		{"pid", 0, 0, KindInt},
+		{"tgid", 32, 0, KindInt},
+		{"comm", 64, 0, KindArray},
+		{"real_parent", 192, 0, KindPointer},
+		{"flags", 256, 0, KindInt}, // in an anonymous union
+		{"raw", 256, 0, KindInt},
+		{"in_execve", 320, 1, KindInt},
+		{"in_iowait", 321, 1, KindInt},
+		{"se", 384, 0, KindStruct},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.member, func(t *testing.T) {
+			m, err := task.Member(tc.member)
+			require.NoError(t, err)
+			assert.Equal(t, tc.offset, m.Offset)
+			assert.Equal(t, tc.bitfieldSize, m.BitfieldSize)
+			assert.Equal(t, tc.kind, m.Type.Kind())
+		})
+	}
+
+	_, err = task.Member("nonexistent")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = spec.FindStruct("pid_type")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// real_parent is a __rcu tagged pointer to task_struct
+	parent, err := task.Member("real_parent")
+	require.NoError(t, err)
+	assert.Equal(t, task, UnderlyingType(parent.Type.(*Pointer).Target))
+
+	comm, err := task.Member("comm")
+	require.NoError(t, err)
+	size, err := Sizeof(comm.Type)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(16), size)
+}
+
+func TestFindMember(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	m, err := spec.FindMember("task_struct", "se.load_weight")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(384+64), m.Offset)
+	assert.Equal(t, uint32(56), m.ByteOffset())
+
+	assert.True(t, spec.FieldExists("task_struct", "se.vruntime"))
+	assert.True(t, spec.FieldExists("task_struct", "flags"))
+	assert.False(t, spec.FieldExists("task_struct", "se.nonexistent"))
+	assert.False(t, spec.FieldExists("task_struct", "pid.nonexistent"))
+	assert.False(t, spec.FieldExists("mm_struct", "pgd"))
+}
+
+func TestFindEnum(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	pidType, err := spec.FindEnum("pid_type")
+	require.NoError(t, err)
+	assert.Equal(t, KindEnum, pidType.Kind())
+	assert.Equal(t, []EnumValue{{"PIDTYPE_PID", 0}, {"PIDTYPE_TGID", 1}, {"PIDTYPE_MAX", 4}}, pidType.Values)
+
+	v, err := pidType.Value("PIDTYPE_MAX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), v)
+
+	bigFlags, err := spec.FindEnum("big_flags")
+	require.NoError(t, err)
+	assert.Equal(t, KindEnum64, bigFlags.Kind())
+	assert.Equal(t, uint32(8), bigFlags.Size)
+
+	v, err = spec.FindEnumValue("BIG_FLAG")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1)<<40, v)
+
+	_, err = spec.FindEnumValue("NONEXISTENT")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFindFunc(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	fn, err := spec.FindFunc("task_pid_nr_ns")
+	require.NoError(t, err)
+	assert.Equal(t, LinkageGlobal, fn.Linkage)
+	assert.False(t, fn.Proto.Variadic())
+
+	ret, err := spec.FindTypedef("pid_t")
+	require.NoError(t, err)
+	assert.Equal(t, Type(ret), fn.Proto.Return)
+
+	require.Len(t, fn.Proto.Params, 2)
+	assert.Equal(t, "task", fn.Proto.Params[0].Name)
+	task := fn.Proto.Params[0].Type.(*Pointer).Target.(*Const).Type
+	assert.Equal(t, "task_struct", task.Name())
+	assert.Equal(t, "pid_type", fn.Proto.Params[1].Type.Name())
+
+	printk, err := spec.FindFunc("printk")
+	require.NoError(t, err)
+	assert.True(t, printk.Proto.Variadic())
+}
+
+func TestOtherKinds(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	fwd, err := spec.Find("mm_struct", KindFwd)
+	require.NoError(t, err)
+	assert.Equal(t, FwdStruct, fwd.(*Fwd).FwdKind)
+
+	double, err := spec.Find("double", KindFloat)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(8), double.(*Float).Size)
+
+	data, err := spec.Find(".data", KindDatasec)
+	require.NoError(t, err)
+	require.Len(t, data.(*Datasec).Vars, 1)
+	assert.Equal(t, "jiffies", data.(*Datasec).Vars[0].Var.Name())
+
+	tag, err := spec.Find("bpf_kfunc", KindDeclTag)
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), tag.(*DeclTag).ComponentIdx)
+	assert.Equal(t, "task_pid_nr_ns", tag.(*DeclTag).Type.Name())
+
+	i, err := spec.Find("int", KindInt)
+	require.NoError(t, err)
+	assert.True(t, i.(*Int).Signed())
+	assert.Equal(t, uint32(32), i.(*Int).Bits)
+
+	void, err := spec.TypeByID(0)
+	require.NoError(t, err)
+	assert.Equal(t, KindUnknown, void.Kind())
+	_, err = spec.TypeByID(TypeID(spec.NumTypes()))
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestSplitSpec(t *testing.T) {
+	base := loadTestSpec(t)
+
+	spec, err := LoadSplitSpec("testdata/module.btf", base)
+	require.NoError(t, err)
+	assert.Greater(t, spec.NumTypes(), base.NumTypes())
+
+	vcpu, err := spec.FindStruct("kvm_vcpu")
+	require.NoError(t, err)
+	assert.Equal(t, TypeID(base.NumTypes()), vcpu.ID())
+
+	// module types refer to vmlinux types
+	owner, err := vcpu.Member("owner")
+	require.NoError(t, err)
+	task, err := spec.FindStruct("task_struct")
+	require.NoError(t, err)
+	assert.Equal(t, Type(task), owner.Type.(*Pointer).Target)
+
+	kick, err := spec.FindFunc("kvm_vcpu_kick")
+	require.NoError(t, err)
+	assert.Equal(t, KindUnknown, kick.Proto.Return.Kind())
+	assert.Equal(t, Type(vcpu), kick.Proto.Params[0].Type.(*Pointer).Target)
+
+	// the base is not changed by the module types
+	_, err = base.FindStruct("kvm_vcpu")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// split BTF can't be parsed on its own
+	_, err = LoadSpec("testdata/module.btf")
+	assert.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/vmlinux.btf")
+	require.NoError(t, err)
+
+	_, err = Parse(data[:10])
+	assert.Error(t, err)
+	_, err = Parse(data[:len(data)-10])
+	assert.Error(t, err)
+
+	bad := append([]byte{}, data...)
+	bad[0] = 0
+	_, err = Parse(bad)
+	assert.Error(t, err)
+}
+
+// rawBTF lays out little-endian BTF with the given type section and the
+// string section "\x00a\x00"
+func rawBTF(types ...uint32) []byte {
+	strs := []byte("\x00a\x00")
+	data := make([]byte, 24+4*len(types))
+	binary.LittleEndian.PutUint16(data, 0xeb9f)
+	data[2] = 1 // version
+	binary.LittleEndian.PutUint32(data[4:], 24)
+	binary.LittleEndian.PutUint32(data[12:], uint32(4*len(types)))
+	binary.LittleEndian.PutUint32(data[16:], uint32(4*len(types)))
+	binary.LittleEndian.PutUint32(data[20:], uint32(len(strs)))
+	for i, v := range types {
+		binary.LittleEndian.PutUint32(data[24+4*i:], v)
+	}
+	return append(data, strs...)
+}
+
+func TestParseCycles(t *testing.T) {
+	info := func(kind Kind, vlen uint32) uint32 {
+		return uint32(kind)<<24 | vlen
+	}
+
+	testCases := map[string][]uint32{
+		// [1] typedef a -> [2] const -> [1]
+		"typedef": {1, info(KindTypedef, 0), 2, 0, info(KindConst, 0), 1},
+		// [1] volatile -> [1]
+		"volatile": {0, info(KindVolatile, 0), 1},
+		// [1] struct a { [1] a; }
+		"struct": {1, info(KindStruct, 1), 4, 1, 1, 0},
+		// [1] int, [2] array of [3] typedef a -> [2]
+		"array": {0, info(KindInt, 0), 4, 32, 0, info(KindArray, 0), 0, 3, 1, 2, 1, info(KindTypedef, 0), 2},
+	}
+	for name, types := range testCases {
+		_, err := Parse(rawBTF(types...))
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), "refers to itself", name)
+		}
+	}
+
+	// [1] struct a { [2] *a; }, [2] pointer -> [1]
+	spec, err := Parse(rawBTF(1, info(KindStruct, 1), 8, 1, 2, 0, 0, info(KindPointer, 0), 1))
+	require.NoError(t, err)
+	s, err := spec.FindStruct("a")
+	require.NoError(t, err)
+	assert.Equal(t, s, s.Members[0].Type.(*Pointer).Target)
+}
+
+func TestKernelSpec(t *testing.T) {
+	if _, err := os.Stat(KernelBTFDir + "/vmlinux"); err != nil {
+		t.Skip("kernel has no BTF")
+	}
+
+	spec, err := LoadKernelSpec()
+	require.NoError(t, err)
+
+	task, err := spec.FindStruct("task_struct")
+	require.NoError(t, err)
+	pid, err := task.Member("pid")
+	require.NoError(t, err)
+	assert.Equal(t, "pid_t", pid.Type.Name())
+
+	modules, err := KernelModules()
+	require.NoError(t, err)
+	if len(modules) > 0 {
+		_, err = LoadKernelModuleSpec(modules[0])
+		assert.NoError(t, err)
+	}
+}
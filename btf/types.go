@@ -0,0 +1,424 @@
+package btf
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TypeID identifies a type within a Spec. ID 0 is void.
+type TypeID uint32
+
+// Kind is the kind of a BTF type (BTF_KIND_*)
+type Kind uint8
+
+const (
+	KindUnknown Kind = iota
+	KindInt
+	KindPointer
+	KindArray
+	KindStruct
+	KindUnion
+	KindEnum
+	KindFwd
+	KindTypedef
+	KindVolatile
+	KindConst
+	KindRestrict
+	KindFunc
+	KindFuncProto
+	KindVar
+	KindDatasec
+	KindFloat
+	KindDeclTag
+	KindTypeTag
+	KindEnum64
+)
+
+func (k Kind) String() string {
+	x := map[Kind]string{
+		KindUnknown:   "unknown",
+		KindInt:       "int",
+		KindPointer:   "ptr",
+		KindArray:     "array",
+		KindStruct:    "struct",
+		KindUnion:     "union",
+		KindEnum:      "enum",
+		KindFwd:       "fwd",
+		KindTypedef:   "typedef",
+		KindVolatile:  "volatile",
+		KindConst:     "const",
+		KindRestrict:  "restrict",
+		KindFunc:      "func",
+		KindFuncProto: "func_proto",
+		KindVar:       "var",
+		KindDatasec:   "datasec",
+		KindFloat:     "float",
+		KindDeclTag:   "decl_tag",
+		KindTypeTag:   "type_tag",
+		KindEnum64:    "enum64",
+	}
+	str, ok := x[k]
+	if !ok {
+		str = "kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return str
+}
+
+// Type is a BTF type. The concrete types are the pointers to the structs
+// of this file: *Int, *Struct, *Enum, ...
+type Type interface {
+	ID() TypeID
+	Name() string // empty for anonymous types
+	Kind() Kind
+}
+
+type typeBase struct {
+	id   TypeID
+	name string
+}
+
+func (t *typeBase) ID() TypeID {
+	return t.id
+}
+
+func (t *typeBase) Name() string {
+	return t.name
+}
+
+// Void is the type with ID 0, referred to by void pointers and functions
+// returning void
+type Void struct {
+	typeBase
+}
+
+func (t *Void) Kind() Kind { return KindUnknown }
+
+// IntEncoding holds the BTF_INT_* flags of an integer
+type IntEncoding uint8
+
+const (
+	IntSigned IntEncoding = 1 << iota
+	IntChar
+	IntBool
+)
+
+type Int struct {
+	typeBase
+	Size     uint32 // in bytes
+	Encoding IntEncoding
+	Offset   uint32 // in bits
+	Bits     uint32
+}
+
+func (t *Int) Kind() Kind { return KindInt }
+
+func (t *Int) Signed() bool {
+	return t.Encoding&IntSigned != 0
+}
+
+type Pointer struct {
+	typeBase
+	Target Type
+}
+
+func (t *Pointer) Kind() Kind { return KindPointer }
+
+type Array struct {
+	typeBase
+	Type      Type // element type
+	IndexType Type
+	Nelems    uint32
+}
+
+func (t *Array) Kind() Kind { return KindArray }
+
+// Member is a member of a struct or a union
+type Member struct {
+	Name         string // empty for anonymous members
+	Type         Type
+	Offset       uint32 // in bits, from the start of the struct or union
+	BitfieldSize uint32 // 0 if not a bitfield
+}
+
+// ByteOffset returns the offset of the member in bytes, rounded down for
+// bitfields
+func (m *Member) ByteOffset() uint32 {
+	return m.Offset / 8
+}
+
+type Struct struct {
+	typeBase
+	Size    uint32 // in bytes
+	Members []Member
+}
+
+func (t *Struct) Kind() Kind { return KindStruct }
+
+// Member looks a member up by name, including within anonymous struct and
+// union members. The offset of the returned member is relative to t.
+func (t *Struct) Member(name string) (*Member, error) {
+	return findMember(t, t.Members, name)
+}
+
+type Union struct {
+	typeBase
+	Size    uint32 // in bytes
+	Members []Member
+}
+
+func (t *Union) Kind() Kind { return KindUnion }
+
+// Member looks a member up by name (see Struct.Member)
+func (t *Union) Member(name string) (*Member, error) {
+	return findMember(t, t.Members, name)
+}
+
+func findMember(parent Type, members []Member, name string) (*Member, error) {
+	if m := lookupMember(members, name); m != nil {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s %s has no member %s: %w", parent.Kind(), parent.Name(), name, ErrNotFound)
+}
+
+func lookupMember(members []Member, name string) *Member {
+	for i := range members {
+		if members[i].Name == name {
+			m := members[i]
+			return &m
+		}
+	}
+	for i := range members {
+		if members[i].Name != "" {
+			continue
+		}
+		var nested []Member
+		switch t := UnderlyingType(members[i].Type).(type) {
+		case *Struct:
+			nested = t.Members
+		case *Union:
+			nested = t.Members
+		default:
+			continue
+		}
+		if m := lookupMember(nested, name); m != nil {
+			m.Offset += members[i].Offset
+			return m
+		}
+	}
+	return nil
+}
+
+// EnumValue is an enumerator. Values of unsigned 64-bit enumerators above
+// math.MaxInt64 wrap around.
+type EnumValue struct {
+	Name  string
+	Value int64
+}
+
+// Enum is an enum, of kind KindEnum or KindEnum64
+type Enum struct {
+	typeBase
+	Size   uint32 // in bytes
+	Signed bool
+	Values []EnumValue
+	is64   bool
+}
+
+func (t *Enum) Kind() Kind {
+	if t.is64 {
+		return KindEnum64
+	}
+	return KindEnum
+}
+
+// Value returns the value of the enumerator name
+func (t *Enum) Value(name string) (int64, error) {
+	for _, v := range t.Values {
+		if v.Name == name {
+			return v.Value, nil
+		}
+	}
+	return 0, fmt.Errorf("enum %s has no value %s: %w", t.name, name, ErrNotFound)
+}
+
+// FwdKind tells what a forward declaration declares
+type FwdKind uint8
+
+const (
+	FwdStruct FwdKind = iota
+	FwdUnion
+)
+
+type Fwd struct {
+	typeBase
+	FwdKind FwdKind
+}
+
+func (t *Fwd) Kind() Kind { return KindFwd }
+
+type Typedef struct {
+	typeBase
+	Type Type
+}
+
+func (t *Typedef) Kind() Kind { return KindTypedef }
+
+type Volatile struct {
+	typeBase
+	Type Type
+}
+
+func (t *Volatile) Kind() Kind { return KindVolatile }
+
+type Const struct {
+	typeBase
+	Type Type
+}
+
+func (t *Const) Kind() Kind { return KindConst }
+
+type Restrict struct {
+	typeBase
+	Type Type
+}
+
+func (t *Restrict) Kind() Kind { return KindRestrict }
+
+// Linkage is the linkage of a function or a variable (BTF_FUNC_* and
+// BTF_VAR_*)
+type Linkage uint32
+
+const (
+	LinkageStatic Linkage = iota
+	LinkageGlobal
+	LinkageExtern
+)
+
+type Func struct {
+	typeBase
+	Proto   *FuncProto
+	Linkage Linkage
+}
+
+func (t *Func) Kind() Kind { return KindFunc }
+
+// FuncParam is a function parameter. A variadic function has a last
+// parameter with no name and a void type.
+type FuncParam struct {
+	Name string
+	Type Type
+}
+
+type FuncProto struct {
+	typeBase
+	Return Type
+	Params []FuncParam
+}
+
+func (t *FuncProto) Kind() Kind { return KindFuncProto }
+
+// Variadic tells whether the function takes a variable number of arguments
+func (t *FuncProto) Variadic() bool {
+	if len(t.Params) == 0 {
+		return false
+	}
+	last := t.Params[len(t.Params)-1]
+	_, void := last.Type.(*Void)
+	return void && last.Name == ""
+}
+
+type Var struct {
+	typeBase
+	Type    Type
+	Linkage Linkage
+}
+
+func (t *Var) Kind() Kind { return KindVar }
+
+// VarSecinfo places a variable within a data section
+type VarSecinfo struct {
+	Var    Type
+	Offset uint32
+	Size   uint32
+}
+
+type Datasec struct {
+	typeBase
+	Size uint32
+	Vars []VarSecinfo
+}
+
+func (t *Datasec) Kind() Kind { return KindDatasec }
+
+type Float struct {
+	typeBase
+	Size uint32 // in bytes
+}
+
+func (t *Float) Kind() Kind { return KindFloat }
+
+// DeclTag tags a declaration (the name of the type is the tag value).
+// ComponentIdx is -1 when the declaration itself is tagged, or the index
+// of the tagged member or parameter.
+type DeclTag struct {
+	typeBase
+	Type         Type
+	ComponentIdx int32
+}
+
+func (t *DeclTag) Kind() Kind { return KindDeclTag }
+
+// TypeTag tags a type (the name of the type is the tag value)
+type TypeTag struct {
+	typeBase
+	Type Type
+}
+
+func (t *TypeTag) Kind() Kind { return KindTypeTag }
+
+// UnderlyingType follows typedefs, type modifiers and type tags
+func UnderlyingType(t Type) Type {
+	for {
+		switch v := t.(type) {
+		case *Typedef:
+			t = v.Type
+		case *Const:
+			t = v.Type
+		case *Volatile:
+			t = v.Type
+		case *Restrict:
+			t = v.Type
+		case *TypeTag:
+			t = v.Type
+		default:
+			return t
+		}
+	}
+}
+
+// Sizeof returns the size of t in bytes
+func Sizeof(t Type) (uint32, error) {
+	switch v := UnderlyingType(t).(type) {
+	case *Int:
+		return v.Size, nil
+	case *Float:
+		return v.Size, nil
+	case *Struct:
+		return v.Size, nil
+	case *Union:
+		return v.Size, nil
+	case *Enum:
+		return v.Size, nil
+	case *Datasec:
+		return v.Size, nil
+	case *Pointer:
+		return strconv.IntSize / 8, nil
+	case *Array:
+		size, err := Sizeof(v.Type)
+		if err != nil {
+			return 0, err
+		}
+		return size * v.Nelems, nil
+	}
+	return 0, fmt.Errorf("%s %s has no size", t.Kind(), t.Name())
+}
@@ -0,0 +1,298 @@
+package btf
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MarshalValue encodes data, a value of type t in byte order bo, as JSON,
+// as bpftool prints values with BTF:
+//   - structs and unions are objects of their members, the members of
+//     anonymous structs and unions inlined
+//   - arrays are arrays, except arrays of char holding a NUL-terminated
+//     printable string, which are strings
+//   - enums are the name of their enumerator, or their value if none
+//     matches
+//   - integers are numbers, bitfields included, except integers of more
+//     than 64 bits, which are hex strings
+//   - pointers are hex strings
+func MarshalValue(t Type, data []byte, bo binary.ByteOrder) ([]byte, error) {
+	return appendValue(nil, t, data, bo)
+}
+
+func appendValue(buf []byte, t Type, data []byte, bo binary.ByteOrder) ([]byte, error) {
+	size, err := Sizeof(t)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(data)) < size {
+		return nil, fmt.Errorf("value of %s %s has %d bytes, expected %d", t.Kind(), t.Name(), len(data), size)
+	}
+	data = data[:size]
+
+	switch v := UnderlyingType(t).(type) {
+	case *Int:
+		if v.Offset != 0 || v.Bits != v.Size*8 {
+			bits, err := readBits(data, v.Offset, v.Bits, bo)
+			if err != nil {
+				return nil, err
+			}
+			return appendInt(buf, v, bits, v.Bits), nil
+		}
+		if v.Size > 8 {
+			return appendHex(buf, data, bo), nil
+		}
+		return appendInt(buf, v, readUint(data, bo), v.Size*8), nil
+
+	case *Enum:
+		return appendEnum(buf, v, readUint(data, bo), v.Size*8), nil
+
+	case *Float:
+		var f float64
+		bitSize := 64
+		switch v.Size {
+		case 4:
+			f, bitSize = float64(math.Float32frombits(bo.Uint32(data))), 32
+		case 8:
+			f = math.Float64frombits(bo.Uint64(data))
+		default:
+			return appendHex(buf, data, bo), nil
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// not numbers in JSON
+			return strconv.AppendQuote(buf, strconv.FormatFloat(f, 'g', -1, bitSize)), nil
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, bitSize), nil
+
+	case *Pointer:
+		return appendHex(buf, data, bo), nil
+
+	case *Array:
+		if s, ok := charString(v, data); ok {
+			return appendString(buf, s), nil
+		}
+		elemSize, err := Sizeof(v.Type)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, '[')
+		for i := uint32(0); i < v.Nelems; i++ {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if buf, err = appendValue(buf, v.Type, data[i*elemSize:], bo); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+
+	case *Struct:
+		return appendMembers(buf, v.Members, data, bo)
+
+	case *Union:
+		return appendMembers(buf, v.Members, data, bo)
+	}
+	return nil, fmt.Errorf("can not encode a value of %s %s", t.Kind(), t.Name())
+}
+
+func appendMembers(buf []byte, members []Member, data []byte, bo binary.ByteOrder) ([]byte, error) {
+	buf = append(buf, '{')
+	buf, _, err := appendMemberList(buf, members, data, bo, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '}'), nil
+}
+
+// appendMemberList appends members as the fields of an object, inlining
+// anonymous structs and unions. first tells whether no field was appended
+// yet, and is returned updated.
+func appendMemberList(buf []byte, members []Member, data []byte, bo binary.ByteOrder, first bool) ([]byte, bool, error) {
+	var err error
+	for _, m := range members {
+		if m.Name == "" && m.BitfieldSize == 0 {
+			var nested []Member
+			switch v := UnderlyingType(m.Type).(type) {
+			case *Struct:
+				nested = v.Members
+			case *Union:
+				nested = v.Members
+			}
+			if nested != nil {
+				if buf, first, err = appendMemberList(buf, nested, data[m.ByteOffset():], bo, first); err != nil {
+					return nil, false, err
+				}
+				continue
+			}
+		}
+		if m.Name == "" {
+			continue // padding bitfields
+		}
+
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendString(buf, m.Name)
+		buf = append(buf, ':')
+
+		if m.BitfieldSize == 0 {
+			buf, err = appendValue(buf, m.Type, data[m.ByteOffset():], bo)
+		} else {
+			buf, err = appendBitfield(buf, m, data, bo)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("member %s: %w", m.Name, err)
+		}
+	}
+	return buf, first, nil
+}
+
+func appendBitfield(buf []byte, m Member, data []byte, bo binary.ByteOrder) ([]byte, error) {
+	bits, err := readBits(data, m.Offset, m.BitfieldSize, bo)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := UnderlyingType(m.Type).(type) {
+	case *Int:
+		return appendInt(buf, v, bits, m.BitfieldSize), nil
+	case *Enum:
+		return appendEnum(buf, v, bits, m.BitfieldSize), nil
+	}
+	return nil, fmt.Errorf("bitfield of %s %s", m.Type.Kind(), m.Type.Name())
+}
+
+func appendInt(buf []byte, t *Int, v uint64, bits uint32) []byte {
+	switch {
+	case t.Encoding&IntBool != 0:
+		return strconv.AppendBool(buf, v != 0)
+	case t.Signed():
+		return strconv.AppendInt(buf, signExtend(v, bits), 10)
+	}
+	return strconv.AppendUint(buf, v, 10)
+}
+
+func appendEnum(buf []byte, t *Enum, v uint64, bits uint32) []byte {
+	value := int64(v)
+	if t.Signed {
+		value = signExtend(v, bits)
+	}
+	for _, e := range t.Values {
+		if e.Value == value {
+			return appendString(buf, e.Name)
+		}
+	}
+	if t.Signed {
+		return strconv.AppendInt(buf, value, 10)
+	}
+	return strconv.AppendUint(buf, v, 10)
+}
+
+// appendHex appends data, a number in byte order bo, as a hex string
+func appendHex(buf []byte, data []byte, bo binary.ByteOrder) []byte {
+	digits := make([]byte, len(data))
+	copy(digits, data)
+	if bo == binary.LittleEndian {
+		for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+			digits[i], digits[j] = digits[j], digits[i]
+		}
+	}
+	return appendString(buf, "0x"+hex.EncodeToString(digits))
+}
+
+func appendString(buf []byte, s string) []byte {
+	quoted, _ := json.Marshal(s)
+	return append(buf, quoted...)
+}
+
+// charString returns the string held by an array of char, if it is
+// NUL-terminated and printable
+func charString(t *Array, data []byte) (string, bool) {
+	elem, ok := UnderlyingType(t.Type).(*Int)
+	if !ok || elem.Size != 1 {
+		return "", false
+	}
+	switch elem.Name() {
+	case "char", "signed char", "unsigned char":
+	default:
+		if elem.Encoding&IntChar == 0 {
+			return "", false
+		}
+	}
+
+	for i, c := range data {
+		if c == 0 {
+			return string(data[:i]), true
+		}
+		if c < 0x20 || c > 0x7e {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// readUint reads an integer of 1, 2, 4 or 8 bytes
+func readUint(data []byte, bo binary.ByteOrder) uint64 {
+	switch len(data) {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(bo.Uint16(data))
+	case 4:
+		return uint64(bo.Uint32(data))
+	}
+	return bo.Uint64(data)
+}
+
+// readBits reads bits bits at bit offset off of data, counted from the
+// least significant bit of the first byte on little-endian, from its most
+// significant bit on big-endian. A 64-bit bitfield not starting on a byte
+// boundary spans 9 bytes.
+func readBits(data []byte, off, bits uint32, bo binary.ByteOrder) (uint64, error) {
+	start, shift := off/8, off%8
+	n := (shift + bits + 7) / 8
+	if bits == 0 || bits > 64 || start+n > uint32(len(data)) {
+		return 0, fmt.Errorf("invalid bitfield of %d bits at bit %d", bits, off)
+	}
+	data = data[start : start+n]
+
+	var v uint64
+	if bo == binary.LittleEndian {
+		for i := uint32(0); i < n && i < 8; i++ {
+			v |= uint64(data[i]) << (8 * i)
+		}
+		v >>= shift
+		if n > 8 {
+			// the top bits are in the 9th byte
+			v |= uint64(data[8]) << (64 - shift)
+		}
+	} else {
+		for i := uint32(0); i < n && i < 8; i++ {
+			v = v<<8 | uint64(data[i])
+		}
+		if n > 8 {
+			// the bottom bits are in the 9th byte
+			low := n*8 - shift - bits
+			v = v<<(8-low) | uint64(data[8])>>low
+		} else {
+			v >>= n*8 - shift - bits
+		}
+	}
+	if bits < 64 {
+		v &= 1<<bits - 1
+	}
+	return v, nil
+}
+
+func signExtend(v uint64, bits uint32) int64 {
+	if bits >= 64 {
+		return int64(v)
+	}
+	shift := 64 - bits
+	return int64(v<<shift) >> shift
+}
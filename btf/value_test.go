@@ -0,0 +1,127 @@
+package btf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalValue(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	task, err := spec.FindStruct("task_struct")
+	require.NoError(t, err)
+
+	data := make([]byte, task.Size)
+	binary.LittleEndian.PutUint32(data[0:], 0xffffffff)
+	binary.LittleEndian.PutUint32(data[4:], 42)
+	copy(data[8:], "bash")
+	binary.LittleEndian.PutUint64(data[24:], 0xffff888012345678)
+	binary.LittleEndian.PutUint32(data[32:], 7)
+	data[40] = 0b10 // in_iowait
+
+	out, err := MarshalValue(task, data, binary.LittleEndian)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"pid": -1,
+		"tgid": 42,
+		"comm": "bash",
+		"real_parent": "0xffff888012345678",
+		"flags": 7,
+		"raw": 7,
+		"in_execve": 0,
+		"in_iowait": 1,
+		"se": {"vruntime": 0, "load_weight": 0}
+	}`, string(out))
+
+	// not a string without NUL
+	comm, err := task.Member("comm")
+	require.NoError(t, err)
+	out, err = MarshalValue(comm.Type, []byte("0123456789abcdef"), binary.LittleEndian)
+	require.NoError(t, err)
+	assert.Equal(t, "[48,49,50,51,52,53,54,55,56,57,97,98,99,100,101,102]", string(out))
+
+	_, err = MarshalValue(task, data[:8], binary.LittleEndian)
+	assert.Error(t, err)
+}
+
+func TestMarshalEnumValue(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	pidType, err := spec.FindEnum("pid_type")
+	require.NoError(t, err)
+	out, err := MarshalValue(pidType, []byte{4, 0, 0, 0}, binary.LittleEndian)
+	require.NoError(t, err)
+	assert.Equal(t, `"PIDTYPE_MAX"`, string(out))
+	out, err = MarshalValue(pidType, []byte{3, 0, 0, 0}, binary.LittleEndian)
+	require.NoError(t, err)
+	assert.Equal(t, `3`, string(out))
+
+	bigFlags, err := spec.FindEnum("big_flags")
+	require.NoError(t, err)
+	out, err = MarshalValue(bigFlags, []byte{0, 0, 0, 0, 0, 1, 0, 0}, binary.LittleEndian)
+	require.NoError(t, err)
+	assert.Equal(t, `"BIG_FLAG"`, string(out))
+
+	double, err := spec.Find("double", KindFloat)
+	require.NoError(t, err)
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, 0x3ff8000000000000)
+	out, err = MarshalValue(double, data, binary.BigEndian)
+	require.NoError(t, err)
+	assert.Equal(t, `1.5`, string(out))
+}
+
+func TestReadBits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		off      uint32
+		bits     uint32
+		bo       binary.ByteOrder
+		expected uint64
+	}{
+		{"little-endian low bits", []byte{0b1011_0110}, 1, 3, binary.LittleEndian, 0b011},
+		{"little-endian across bytes", []byte{0xf0, 0x0f}, 4, 8, binary.LittleEndian, 0xff},
+		{"big-endian high bits", []byte{0b1011_0110}, 1, 3, binary.BigEndian, 0b011},
+		{"big-endian across bytes", []byte{0x0f, 0xf0}, 4, 8, binary.BigEndian, 0xff},
+		{"whole word", []byte{1, 2, 3, 4, 5, 6, 7, 8}, 0, 64, binary.LittleEndian, 0x0807060504030201},
+		{
+			"little-endian 64 bits across 9 bytes",
+			[]byte{0xfa, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12, 0x5f}, 4, 64,
+			binary.LittleEndian, 0xf123456789abcdef,
+		},
+		{
+			"big-endian 64 bits across 9 bytes",
+			[]byte{0xf8, 0x91, 0xa2, 0xb3, 0xc4, 0xd5, 0xe6, 0xf7, 0xd5}, 1, 64,
+			binary.BigEndian, 0xf123456789abcdef,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := readBits(tc.data, tc.off, tc.bits, tc.bo)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+
+	_, err := readBits([]byte{0}, 4, 8, binary.LittleEndian)
+	assert.Error(t, err)
+	_, err = readBits(make([]byte, 8), 4, 64, binary.LittleEndian)
+	assert.Error(t, err)
+
+	// struct __attribute__((packed)) { u8 a:4; u64 b:64; }
+	packed := &Struct{
+		Size: 9,
+		Members: []Member{
+			{Name: "a", Type: &Int{Size: 1, Bits: 8}, Offset: 0, BitfieldSize: 4},
+			{Name: "b", Type: &Int{Size: 8, Bits: 64}, Offset: 4, BitfieldSize: 64},
+		},
+	}
+	out, err := MarshalValue(packed, []byte{0xfa, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12, 0x5f}, binary.LittleEndian)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":10,"b":17375808098319191535}`, string(out))
+	assert.Equal(t, int64(-2), signExtend(0b110, 3))
+}
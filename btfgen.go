@@ -0,0 +1,703 @@
+package libbpfgo
+
+/*
+#include <stdlib.h>
+
+#include <bpf/btf.h>
+#include <bpf/libbpf.h>
+
+static __u32 btfgen_type_ref(const struct btf_type *t)
+{
+    return t->type;
+}
+
+static __u32 btfgen_type_size(const struct btf_type *t)
+{
+    return t->size;
+}
+
+static const struct btf_member *btfgen_member(const struct btf_type *t, int i)
+{
+    return btf_members(t) + i;
+}
+
+static const struct btf_enum *btfgen_enum(const struct btf_type *t, int i)
+{
+    return btf_enum(t) + i;
+}
+
+static const struct btf_enum64 *btfgen_enum64(const struct btf_type *t, int i)
+{
+    return btf_enum64(t) + i;
+}
+
+// btfgen_set_enum_signed sets the signedness kflag of the enum id, which
+// btf__add_enum() does not take
+static void btfgen_set_enum_signed(struct btf *btf, int id, bool is_signed)
+{
+    struct btf_type *t = (struct btf_type *) btf__type_by_id(btf, id);
+
+    t->info = (t->info & ~(1U << 31)) | ((__u32) is_signed << 31);
+}
+
+static const struct btf_param *btfgen_param(const struct btf_type *t, int i)
+{
+    return btf_params(t) + i;
+}
+*/
+import "C"
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// GenerateMinimalBTF writes to outPath a BTF file holding only the types of
+// the BTF at vmlinuxBTFPath that the CO-RE relocations of the BPF objects
+// at objPaths need, like "bpftool gen min_core_btf" does. The result is a
+// few KB instead of a few MB, and can be given to NewModuleArgs.BTFObjPath
+// to load these objects on the kernel the BTF describes.
+//
+// Structs and unions only keep the members the objects access; types that
+// are only reachable through pointers are replaced with void.
+func GenerateMinimalBTF(vmlinuxBTFPath, outPath string, objPaths ...string) error {
+	btfFile := C.CString(vmlinuxBTFPath)
+	defer C.free(unsafe.Pointer(btfFile))
+
+	targ := C.btf__parse(btfFile, nil)
+	if errC := C.libbpf_get_error(unsafe.Pointer(targ)); errC != 0 {
+		return fmt.Errorf("failed to parse BTF %s: %w", vmlinuxBTFPath, syscall.Errno(-errC))
+	}
+	defer C.btf__free(targ)
+
+	g := newBTFGen(targ)
+	for _, objPath := range objPaths {
+		if err := g.addObject(objPath); err != nil {
+			return err
+		}
+	}
+
+	data, err := g.build()
+	if err != nil {
+		return fmt.Errorf("failed to generate BTF %s: %w", outPath, err)
+	}
+
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// coreReloKind is the kind of a CO-RE relocation (enum bpf_core_relo_kind)
+type coreReloKind uint32
+
+const (
+	coreFieldByteOffset coreReloKind = iota
+	coreFieldByteSize
+	coreFieldExists
+	coreFieldSigned
+	coreFieldLShiftU64
+	coreFieldRShiftU64
+	coreTypeIDLocal
+	coreTypeIDTarget
+	coreTypeExists
+	coreTypeSize
+	coreEnumvalExists
+	coreEnumvalValue
+	coreTypeMatches
+)
+
+// coreRelo is a CO-RE relocation record (struct bpf_core_relo), as found
+// in the .BTF.ext section of a BPF object
+type coreRelo struct {
+	typeID       uint32
+	accessStrOff uint32
+	kind         coreReloKind
+}
+
+const btfExtMagic = 0xeb9f
+
+// parseCoreRelos extracts the CO-RE relocation records of all the programs
+// from the content of a .BTF.ext section
+func parseCoreRelos(data []byte) ([]coreRelo, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid .BTF.ext header")
+	}
+
+	var bo binary.ByteOrder = binary.LittleEndian
+	if bo.Uint16(data) != btfExtMagic {
+		bo = binary.BigEndian
+		if bo.Uint16(data) != btfExtMagic {
+			return nil, errors.New("invalid .BTF.ext magic")
+		}
+	}
+
+	// CO-RE relocations are described by the optional part of the header
+	hdrLen := bo.Uint32(data[4:])
+	if hdrLen < 32 {
+		return nil, nil
+	}
+	if uint64(len(data)) < uint64(hdrLen) {
+		return nil, errors.New("invalid .BTF.ext header length")
+	}
+
+	off := uint64(hdrLen) + uint64(bo.Uint32(data[24:]))
+	length := uint64(bo.Uint32(data[28:]))
+	if length == 0 {
+		return nil, nil
+	}
+	if off+length > uint64(len(data)) || length < 4 {
+		return nil, errors.New("invalid .BTF.ext CO-RE relocation section")
+	}
+	info := data[off : off+length]
+
+	recSize := uint64(bo.Uint32(info))
+	if recSize < 16 {
+		return nil, fmt.Errorf("invalid .BTF.ext CO-RE relocation record size %d", recSize)
+	}
+	info = info[4:]
+
+	var relos []coreRelo
+	for len(info) > 0 {
+		if len(info) < 8 {
+			return nil, errors.New("truncated .BTF.ext CO-RE relocation section")
+		}
+		numInfo := uint64(bo.Uint32(info[4:]))
+		info = info[8:]
+		if numInfo*recSize > uint64(len(info)) {
+			return nil, errors.New("truncated .BTF.ext CO-RE relocation section")
+		}
+		for i := uint64(0); i < numInfo; i++ {
+			rec := info[i*recSize:]
+			relos = append(relos, coreRelo{
+				typeID:       bo.Uint32(rec[4:]),
+				accessStrOff: bo.Uint32(rec[8:]),
+				kind:         coreReloKind(bo.Uint32(rec[12:])),
+			})
+		}
+		info = info[numInfo*recSize:]
+	}
+
+	return relos, nil
+}
+
+// parseAccessStr parses the access string of a CO-RE relocation, e.g.
+// "0:1:2" (array index of the root type, then member or array indexes)
+func parseAccessStr(s string) ([]int, error) {
+	var access []int
+	for _, part := range strings.Split(s, ":") {
+		idx, err := strconv.Atoi(part)
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("invalid access string %q", s)
+		}
+		access = append(access, idx)
+	}
+	return access, nil
+}
+
+// essentialName strips the "___flavor" suffix CO-RE ignores when matching
+// type, member and enumerator names
+func essentialName(name string) string {
+	if i := strings.Index(name, "___"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func btfName(btf *C.struct_btf, off C.__u32) string {
+	return C.GoString(C.btf__name_by_offset(btf, off))
+}
+
+// btfSkipMods follows typedefs and type modifiers
+func btfSkipMods(btf *C.struct_btf, id uint32) uint32 {
+	for {
+		t := C.btf__type_by_id(btf, C.uint(id))
+		if t == nil {
+			return 0
+		}
+		switch C.btf_kind(t) {
+		case C.BTF_KIND_TYPEDEF, C.BTF_KIND_CONST, C.BTF_KIND_VOLATILE,
+			C.BTF_KIND_RESTRICT, C.BTF_KIND_TYPE_TAG:
+			id = uint32(C.btfgen_type_ref(t))
+		default:
+			return id
+		}
+	}
+}
+
+func btfIsComposite(kind C.__u16) bool {
+	return kind == C.BTF_KIND_STRUCT || kind == C.BTF_KIND_UNION
+}
+
+// btfIsAnyEnum tells whether kind is ENUM or ENUM64, which CO-RE considers
+// the same kind
+func btfIsAnyEnum(kind C.__u16) bool {
+	return kind == C.BTF_KIND_ENUM || kind == C.BTF_KIND_ENUM64
+}
+
+// btfEnumName returns the name of the i-th enumerator of the ENUM or ENUM64 t
+func btfEnumName(btf *C.struct_btf, t *C.struct_btf_type, i int) string {
+	if C.btf_kind(t) == C.BTF_KIND_ENUM64 {
+		return btfName(btf, C.btfgen_enum64(t, C.int(i)).name_off)
+	}
+	return btfName(btf, C.btfgen_enum(t, C.int(i)).name_off)
+}
+
+// btfFieldsCompat mimics the field compatibility check libbpf does when
+// relocating a field access
+func btfFieldsCompat(local *C.struct_btf, localID uint32, targ *C.struct_btf, targID uint32) bool {
+	localID, targID = btfSkipMods(local, localID), btfSkipMods(targ, targID)
+	lt, tt := C.btf__type_by_id(local, C.uint(localID)), C.btf__type_by_id(targ, C.uint(targID))
+	if lt == nil || tt == nil {
+		return false
+	}
+
+	lk, tk := C.btf_kind(lt), C.btf_kind(tt)
+	if btfIsComposite(lk) && btfIsComposite(tk) {
+		return true
+	}
+	if btfIsAnyEnum(lk) && btfIsAnyEnum(tk) {
+		return true
+	}
+	if lk != tk {
+		return false
+	}
+
+	switch lk {
+	case C.BTF_KIND_INT, C.BTF_KIND_FLOAT, C.BTF_KIND_PTR, C.BTF_KIND_FWD:
+		return true
+	case C.BTF_KIND_ARRAY:
+		return btfFieldsCompat(local, uint32(C.btf_array(lt)._type), targ, uint32(C.btf_array(tt)._type))
+	}
+	return false
+}
+
+// btfgenType is a target type kept in the minimized BTF
+type btfgenType struct {
+	followed   bool         // pointers were followed when marking it
+	members    map[int]bool // kept members, for structs and unions
+	allMembers bool
+}
+
+// btfgenMember is a member of a target struct or union
+type btfgenMember struct {
+	parent uint32
+	index  int
+	typeID uint32
+}
+
+type btfgen struct {
+	targ  *C.struct_btf
+	cands map[string][]uint32 // target type IDs by kind and essential name
+	types map[uint32]*btfgenType
+}
+
+func newBTFGen(targ *C.struct_btf) *btfgen {
+	g := &btfgen{
+		targ:  targ,
+		cands: make(map[string][]uint32),
+		types: make(map[uint32]*btfgenType),
+	}
+
+	for id := uint32(1); id < uint32(C.btf__type_cnt(targ)); id++ {
+		t := C.btf__type_by_id(targ, C.uint(id))
+		name := essentialName(btfName(targ, t.name_off))
+		if name == "" {
+			continue
+		}
+		key := candKey(C.btf_kind(t), name)
+		g.cands[key] = append(g.cands[key], id)
+	}
+
+	return g
+}
+
+func candKey(kind C.__u16, name string) string {
+	if btfIsAnyEnum(kind) {
+		kind = C.BTF_KIND_ENUM // candidates of either kind
+	}
+	return fmt.Sprintf("%d:%s", kind, name)
+}
+
+// addObject marks the target types used by the CO-RE relocations of the
+// BPF object at path
+func (g *btfgen) addObject(path string) error {
+	relos, err := readCoreRelos(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CO-RE relocations of %s: %w", path, err)
+	}
+	if len(relos) == 0 {
+		return nil
+	}
+
+	objFile := C.CString(path)
+	defer C.free(unsafe.Pointer(objFile))
+
+	local := C.btf__parse_elf(objFile, nil)
+	if errC := C.libbpf_get_error(unsafe.Pointer(local)); errC != 0 {
+		return fmt.Errorf("failed to parse BTF of %s: %w", path, syscall.Errno(-errC))
+	}
+	defer C.btf__free(local)
+
+	for _, relo := range relos {
+		if err = g.addRelo(local, relo); err != nil {
+			return fmt.Errorf("failed to process CO-RE relocation of %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func readCoreRelos(path string) ([]coreRelo, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sec := f.Section(".BTF.ext")
+	if sec == nil {
+		return nil, nil
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return nil, err
+	}
+	return parseCoreRelos(data)
+}
+
+func (g *btfgen) addRelo(local *C.struct_btf, relo coreRelo) error {
+	if relo.kind == coreTypeIDLocal {
+		return nil // relocated against the object own BTF
+	}
+
+	t := C.btf__type_by_id(local, C.uint(relo.typeID))
+	if t == nil {
+		return fmt.Errorf("invalid type ID %d", relo.typeID)
+	}
+	name := essentialName(btfName(local, t.name_off))
+	if name == "" {
+		return fmt.Errorf("type %d is anonymous", relo.typeID)
+	}
+	access, err := parseAccessStr(btfName(local, C.__u32(relo.accessStrOff)))
+	if err != nil {
+		return err
+	}
+
+	// a type missing from the target is not an error: the object may
+	// check its existence before using it
+	for _, cand := range g.cands[candKey(C.btf_kind(t), name)] {
+		switch relo.kind {
+		case coreFieldByteOffset, coreFieldByteSize, coreFieldExists,
+			coreFieldSigned, coreFieldLShiftU64, coreFieldRShiftU64:
+			g.markField(local, relo.typeID, access, cand)
+		case coreTypeIDTarget, coreTypeExists, coreTypeSize, coreTypeMatches:
+			g.markType(cand, true)
+			g.markAllMembers(cand)
+		case coreEnumvalExists, coreEnumvalValue:
+			g.markEnumval(local, relo.typeID, access, cand)
+		default:
+			return fmt.Errorf("unknown relocation kind %d", relo.kind)
+		}
+	}
+
+	return nil
+}
+
+// markField walks the access string on the local type and on the target
+// candidate, matching members by name, and marks the target members if the
+// access can be relocated to that candidate
+func (g *btfgen) markField(local *C.struct_btf, localRoot uint32, access []int, targRoot uint32) {
+	lid := btfSkipMods(local, localRoot)
+	tid := btfSkipMods(g.targ, targRoot)
+
+	var path []btfgenMember
+	for _, idx := range access[1:] {
+		lt := C.btf__type_by_id(local, C.uint(lid))
+		tt := C.btf__type_by_id(g.targ, C.uint(tid))
+
+		switch C.btf_kind(lt) {
+		case C.BTF_KIND_STRUCT, C.BTF_KIND_UNION:
+			if idx >= int(C.btf_vlen(lt)) {
+				return
+			}
+			lm := C.btfgen_member(lt, C.int(idx))
+			lid = btfSkipMods(local, uint32(lm._type))
+
+			name := essentialName(btfName(local, lm.name_off))
+			if name == "" {
+				continue // anonymous members are found by name of what they contain
+			}
+			members := g.findMember(tid, name)
+			if members == nil {
+				return
+			}
+			found := members[len(members)-1]
+			if !btfFieldsCompat(local, lid, g.targ, found.typeID) {
+				return
+			}
+			path = append(path, members...)
+			tid = btfSkipMods(g.targ, found.typeID)
+
+		case C.BTF_KIND_ARRAY:
+			if C.btf_kind(tt) != C.BTF_KIND_ARRAY {
+				return
+			}
+			lid = btfSkipMods(local, uint32(C.btf_array(lt)._type))
+			tid = btfSkipMods(g.targ, uint32(C.btf_array(tt)._type))
+
+		default:
+			return
+		}
+	}
+
+	g.markType(targRoot, false)
+	for _, member := range path {
+		g.markType(member.parent, false)
+		g.types[member.parent].members[member.index] = true
+		g.markType(member.typeID, false)
+	}
+}
+
+// findMember looks a member up by name in the target struct or union id,
+// looking into anonymous members as well. It returns the members leading to
+// the one found, or nil.
+func (g *btfgen) findMember(id uint32, name string) []btfgenMember {
+	t := C.btf__type_by_id(g.targ, C.uint(id))
+	if t == nil || !btfIsComposite(C.btf_kind(t)) {
+		return nil
+	}
+
+	for i := 0; i < int(C.btf_vlen(t)); i++ {
+		m := C.btfgen_member(t, C.int(i))
+		member := btfgenMember{parent: id, index: i, typeID: uint32(m._type)}
+
+		memberName := essentialName(btfName(g.targ, m.name_off))
+		if memberName == name {
+			return []btfgenMember{member}
+		}
+		if memberName == "" {
+			if sub := g.findMember(btfSkipMods(g.targ, member.typeID), name); sub != nil {
+				return append([]btfgenMember{member}, sub...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// markEnumval marks the target enum if it has the enumerator the local
+// relocation refers to
+func (g *btfgen) markEnumval(local *C.struct_btf, localRoot uint32, access []int, targRoot uint32) {
+	lt := C.btf__type_by_id(local, C.uint(btfSkipMods(local, localRoot)))
+	tt := C.btf__type_by_id(g.targ, C.uint(btfSkipMods(g.targ, targRoot)))
+	if !btfIsAnyEnum(C.btf_kind(lt)) || !btfIsAnyEnum(C.btf_kind(tt)) {
+		return
+	}
+	if access[0] >= int(C.btf_vlen(lt)) {
+		return
+	}
+
+	name := essentialName(btfEnumName(local, lt, access[0]))
+	for i := 0; i < int(C.btf_vlen(tt)); i++ {
+		if essentialName(btfEnumName(g.targ, tt, i)) == name {
+			g.markType(targRoot, false)
+			return
+		}
+	}
+}
+
+// markType keeps the target type id. Structs and unions are kept without
+// members, which are marked separately. Pointed types are only kept when
+// followPtrs is set, pointers to anything else become void pointers.
+func (g *btfgen) markType(id uint32, followPtrs bool) {
+	if id == 0 {
+		return
+	}
+	if info, ok := g.types[id]; ok && (info.followed || !followPtrs) {
+		return
+	}
+	g.types[id] = &btfgenType{
+		followed: followPtrs,
+		members:  make(map[int]bool),
+	}
+
+	t := C.btf__type_by_id(g.targ, C.uint(id))
+	switch C.btf_kind(t) {
+	case C.BTF_KIND_PTR:
+		if followPtrs {
+			g.markType(uint32(C.btfgen_type_ref(t)), followPtrs)
+		}
+	case C.BTF_KIND_TYPEDEF, C.BTF_KIND_CONST, C.BTF_KIND_VOLATILE,
+		C.BTF_KIND_RESTRICT, C.BTF_KIND_TYPE_TAG:
+		g.markType(uint32(C.btfgen_type_ref(t)), followPtrs)
+	case C.BTF_KIND_ARRAY:
+		g.markType(uint32(C.btf_array(t)._type), followPtrs)
+		g.markType(uint32(C.btf_array(t).index_type), followPtrs)
+	case C.BTF_KIND_FUNC_PROTO:
+		g.markType(uint32(C.btfgen_type_ref(t)), followPtrs)
+		for i := 0; i < int(C.btf_vlen(t)); i++ {
+			g.markType(uint32(C.btfgen_param(t, C.int(i))._type), followPtrs)
+		}
+	}
+}
+
+// markAllMembers keeps every member of the target struct or union id, for
+// relocations on the type itself (existence, size, ...)
+func (g *btfgen) markAllMembers(id uint32) {
+	id = btfSkipMods(g.targ, id)
+	t := C.btf__type_by_id(g.targ, C.uint(id))
+	if !btfIsComposite(C.btf_kind(t)) {
+		return
+	}
+
+	g.markType(id, false)
+	g.types[id].allMembers = true
+	for i := 0; i < int(C.btf_vlen(t)); i++ {
+		g.markType(uint32(C.btfgen_member(t, C.int(i))._type), false)
+	}
+}
+
+// build creates the minimized BTF out of the marked types, which keep
+// their relative order
+func (g *btfgen) build() ([]byte, error) {
+	ids := make([]uint32, 0, len(g.types))
+	for id := range g.types {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	newIDs := make(map[uint32]C.int, len(ids))
+	for i, id := range ids {
+		newIDs[id] = C.int(i + 1)
+	}
+
+	btf := C.btf__new_empty()
+	if errC := C.libbpf_get_error(unsafe.Pointer(btf)); errC != 0 {
+		return nil, fmt.Errorf("failed to create BTF: %w", syscall.Errno(-errC))
+	}
+	defer C.btf__free(btf)
+
+	for i, id := range ids {
+		newID := g.addType(btf, id, newIDs)
+		if newID < 0 {
+			return nil, fmt.Errorf("failed to add type %d: %w", id, syscall.Errno(-newID))
+		}
+		if newID != C.int(i+1) {
+			return nil, fmt.Errorf("failed to add type %d: unexpected new ID %d", id, newID)
+		}
+	}
+
+	var size C.__u32
+	data := C.btf__raw_data(btf, &size)
+	if data == nil {
+		return nil, fmt.Errorf("failed to get raw BTF data: %w", syscall.ENOMEM)
+	}
+	return C.GoBytes(data, C.int(size)), nil
+}
+
+// addType copies the target type id to btf, and returns its new ID or a
+// negative error. References to types not kept become references to void.
+func (g *btfgen) addType(btf *C.struct_btf, id uint32, newIDs map[uint32]C.int) C.int {
+	ref := func(id C.__u32) C.int {
+		return newIDs[uint32(id)]
+	}
+
+	t := C.btf__type_by_id(g.targ, C.uint(id))
+	name := C.btf__name_by_offset(g.targ, t.name_off)
+
+	switch C.btf_kind(t) {
+	case C.BTF_KIND_INT:
+		return C.btf__add_int(btf, name, C.size_t(C.btfgen_type_size(t)), C.int(C.btf_int_encoding(t)))
+	case C.BTF_KIND_FLOAT:
+		return C.btf__add_float(btf, name, C.size_t(C.btfgen_type_size(t)))
+	case C.BTF_KIND_PTR:
+		return C.btf__add_ptr(btf, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_TYPEDEF:
+		return C.btf__add_typedef(btf, name, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_CONST:
+		return C.btf__add_const(btf, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_VOLATILE:
+		return C.btf__add_volatile(btf, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_RESTRICT:
+		return C.btf__add_restrict(btf, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_TYPE_TAG:
+		return C.btf__add_type_tag(btf, name, ref(C.btfgen_type_ref(t)))
+	case C.BTF_KIND_ARRAY:
+		arr := C.btf_array(t)
+		return C.btf__add_array(btf, ref(arr.index_type), ref(arr._type), arr.nelems)
+	case C.BTF_KIND_FWD:
+		kind := uint32(C.BTF_FWD_STRUCT)
+		if C.btf_kflag(t) {
+			kind = C.BTF_FWD_UNION
+		}
+		return C.btf__add_fwd(btf, name, kind)
+
+	case C.BTF_KIND_ENUM:
+		signed := bool(C.btf_kflag(t))
+		newID := C.btf__add_enum(btf, name, C.btfgen_type_size(t))
+		for i := 0; i < int(C.btf_vlen(t)) && newID > 0; i++ {
+			e := C.btfgen_enum(t, C.int(i))
+			value := C.__s64(uint32(e.val))
+			if signed {
+				value = C.__s64(e.val)
+			}
+			if errC := C.btf__add_enum_value(btf, C.btf__name_by_offset(g.targ, e.name_off), value); errC < 0 {
+				return errC
+			}
+		}
+		if newID > 0 {
+			// btf__add_enum_value only flags enums with negative values
+			C.btfgen_set_enum_signed(btf, newID, C.bool(signed))
+		}
+		return newID
+
+	case C.BTF_KIND_ENUM64:
+		newID := C.btf__add_enum64(btf, name, C.btfgen_type_size(t), C.bool(C.btf_kflag(t)))
+		for i := 0; i < int(C.btf_vlen(t)) && newID > 0; i++ {
+			e := C.btfgen_enum64(t, C.int(i))
+			if errC := C.btf__add_enum64_value(btf, C.btf__name_by_offset(g.targ, e.name_off), C.btf_enum64_value(e)); errC < 0 {
+				return errC
+			}
+		}
+		return newID
+
+	case C.BTF_KIND_STRUCT, C.BTF_KIND_UNION:
+		var newID C.int
+		if C.btf_kind(t) == C.BTF_KIND_STRUCT {
+			newID = C.btf__add_struct(btf, name, C.btfgen_type_size(t))
+		} else {
+			newID = C.btf__add_union(btf, name, C.btfgen_type_size(t))
+		}
+		info := g.types[id]
+		for i := 0; i < int(C.btf_vlen(t)) && newID > 0; i++ {
+			if !info.allMembers && !info.members[i] {
+				continue
+			}
+			m := C.btfgen_member(t, C.int(i))
+			errC := C.btf__add_field(btf, C.btf__name_by_offset(g.targ, m.name_off), ref(m._type),
+				C.btf_member_bit_offset(t, C.__u32(i)), C.btf_member_bitfield_size(t, C.__u32(i)))
+			if errC < 0 {
+				return errC
+			}
+		}
+		return newID
+
+	case C.BTF_KIND_FUNC_PROTO:
+		newID := C.btf__add_func_proto(btf, ref(C.btfgen_type_ref(t)))
+		for i := 0; i < int(C.btf_vlen(t)) && newID > 0; i++ {
+			p := C.btfgen_param(t, C.int(i))
+			if errC := C.btf__add_func_param(btf, C.btf__name_by_offset(g.targ, p.name_off), ref(p._type)); errC < 0 {
+				return errC
+			}
+		}
+		return newID
+	}
+
+	return -C.int(syscall.EINVAL)
+}
@@ -0,0 +1,84 @@
+package libbpfgo
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseCoreRelos(t *testing.T) {
+	bo := binary.LittleEndian
+	u32 := func(b []byte, v ...uint32) []byte {
+		for _, x := range v {
+			var buf [4]byte
+			bo.PutUint32(buf[:], x)
+			b = append(b, buf[:]...)
+		}
+		return b
+	}
+
+	// core_relo section: record size, then one section with 2 records
+	relo := u32(nil, 16)
+	relo = u32(relo, 1, 2)          // sec_name_off, num_info
+	relo = u32(relo, 8, 3, 10, 0)   // insn_off, type_id, access_str_off, kind
+	relo = u32(relo, 16, 4, 14, 8)  // TYPE_EXISTS
+	funcInfo := u32(nil, 8, 1, 0)   // func info, skipped
+	hdr := []byte{0x9f, 0xeb, 1, 0} // magic, version, flags
+	hdr = u32(hdr, 32)              // hdr_len
+	hdr = u32(hdr, 0, uint32(len(funcInfo)), uint32(len(funcInfo)), 0)
+	hdr = u32(hdr, uint32(len(funcInfo)), uint32(len(relo)))
+
+	data := append(append(hdr, funcInfo...), relo...)
+	relos, err := parseCoreRelos(data)
+	if err != nil {
+		t.Fatalf("failed to parse CO-RE relocations: %v", err)
+	}
+	expected := []coreRelo{
+		{typeID: 3, accessStrOff: 10, kind: coreFieldByteOffset},
+		{typeID: 4, accessStrOff: 14, kind: coreTypeExists},
+	}
+	if !reflect.DeepEqual(relos, expected) {
+		t.Errorf("expected %+v, got %+v", expected, relos)
+	}
+
+	// no CO-RE relocations in the header
+	if relos, err = parseCoreRelos(data[:24]); err == nil || relos != nil {
+		t.Errorf("truncated header should fail")
+	}
+	short := append([]byte{}, data...)
+	bo.PutUint32(short[4:], 24)
+	if relos, err = parseCoreRelos(short); err != nil || relos != nil {
+		t.Errorf("header without CO-RE relocations: got %v, %v", relos, err)
+	}
+
+	// truncated records
+	if _, err = parseCoreRelos(data[:len(data)-4]); err == nil {
+		t.Errorf("truncated CO-RE relocation section should fail")
+	}
+}
+
+func TestParseAccessStr(t *testing.T) {
+	access, err := parseAccessStr("0:12:3")
+	if err != nil || !reflect.DeepEqual(access, []int{0, 12, 3}) {
+		t.Errorf("unexpected access: %v, %v", access, err)
+	}
+	for _, s := range []string{"", "0:", "0:-1", "a"} {
+		if _, err = parseAccessStr(s); err == nil {
+			t.Errorf("access string %q should be invalid", s)
+		}
+	}
+}
+
+func TestEssentialName(t *testing.T) {
+	testCases := map[string]string{
+		"task_struct":        "task_struct",
+		"task_struct___v510": "task_struct",
+		"___x":               "___x",
+		"a___b___c":          "a",
+	}
+	for name, expected := range testCases {
+		if got := essentialName(name); got != expected {
+			t.Errorf("essentialName(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Command libbpfgo-skel generates a typed Go skeleton for a BPF object file.
+//
+// The object is opened with libbpf and the generated file contains a struct
+// with a field for every map, program and global variable of the object,
+// plus Load, Attach and Close methods built on top of libbpfgo:
+//
+//	libbpfgo-skel -obj main.bpf.o -out main_skel.go -pkg main -embed
+//
+// With -embed, the object bytes are embedded with go:embed (the object must
+// then live in the directory of the generated file, or below it) and opened
+// with NewModuleFromBufferArgs. Otherwise, it is opened from its path with
+// NewModuleFromFileArgs.
+//
+// It is meant to be run from go:generate, for example:
+//
+//	//go:generate libbpfgo-skel -obj main.bpf.o -out main_skel.go -pkg main
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"unicode"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+// autoAttachPrefixes are the section prefixes libbpf knows how to attach
+// without any extra information (see bpf_program__attach())
+var autoAttachPrefixes = []string{
+	"kprobe/", "kretprobe/",
+	"tracepoint/", "tp/",
+	"raw_tracepoint/", "raw_tp/",
+	"tp_btf/", "fentry/", "fexit/", "fmod_ret/", "fentry.s/", "fexit.s/", "fmod_ret.s/",
+	"lsm/", "lsm.s/",
+	"iter/", "iter.s/",
+}
+
+type symbol struct {
+	Name  string // name in the BPF object
+	Field string // Go field name
+}
+
+type skeleton struct {
+	Tool       string
+	ObjFile    string
+	ObjName    string
+	Package    string
+	Name       string
+	Embed      bool
+	EmbedPath  string
+	EmbedVar   string
+	Maps       []symbol
+	Progs      []symbol
+	AutoAttach []symbol
+	Vars       []symbol
+}
+
+func main() {
+	objPath := flag.String("obj", "", "BPF object file (required)")
+	outPath := flag.String("out", "", "generated Go file (default: stdout)")
+	pkgName := flag.String("pkg", "main", "package of the generated file")
+	typeName := flag.String("name", "", "name of the skeleton type (default: derived from the object name)")
+	embed := flag.Bool("embed", false, "embed the object bytes in the generated file")
+	flag.Parse()
+
+	if *objPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := generate(*objPath, *outPath, *pkgName, *typeName, *embed); err != nil {
+		fmt.Fprintf(os.Stderr, "libbpfgo-skel: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(objPath, outPath, pkgName, typeName string, embed bool) error {
+	module, err := bpf.NewModuleFromFileArgs(bpf.NewModuleArgs{
+		BPFObjPath:      objPath,
+		SkipMemlockBump: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer module.Close()
+
+	skel := skeleton{
+		Tool:    "libbpfgo-skel",
+		ObjFile: filepath.Base(objPath),
+		ObjName: module.Name(),
+		Package: pkgName,
+		Name:    typeName,
+		Embed:   embed,
+	}
+	if skel.Name == "" {
+		skel.Name = goName(skel.ObjName)
+	}
+	skel.EmbedVar = strings.ToLower(skel.Name[:1]) + skel.Name[1:] + "Object"
+
+	if embed {
+		skel.EmbedPath, err = embedPath(objPath, outPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := make(map[string]bool)
+
+	it := module.Iterator()
+	for m := it.NextMap(); m != nil; m = it.NextMap() {
+		if strings.Contains(m.Name(), ".") {
+			continue // internal maps (.data, .bss, ...) are exposed through Vars
+		}
+		skel.Maps = append(skel.Maps, symbol{m.Name(), uniqueName(fields, "Maps", goName(m.Name()))})
+	}
+	for p := it.NextProgram(); p != nil; p = it.NextProgram() {
+		prog := symbol{p.GetName(), uniqueName(fields, "Progs", goName(p.GetName()))}
+		skel.Progs = append(skel.Progs, prog)
+		if autoAttach(p.GetSectionName()) {
+			skel.AutoAttach = append(skel.AutoAttach, prog)
+		}
+	}
+
+	vars, err := module.GetGlobalVariables()
+	if err != nil && !errors.Is(err, syscall.ENOENT) { // ENOENT: no BTF, no variables
+		return err
+	}
+	for _, v := range vars {
+		if v.Section() == ".kconfig" || v.Section() == ".ksyms" {
+			continue // externs, resolved by libbpf
+		}
+		skel.Vars = append(skel.Vars, symbol{v.Name(), uniqueName(fields, "Vars", goName(v.Name()))})
+	}
+
+	var buf bytes.Buffer
+	if err = skeletonTemplate.Execute(&buf, skel); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %v", err)
+	}
+
+	if outPath == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(outPath, src, 0644)
+}
+
+// embedPath returns the path of the object relative to the generated file,
+// as go:embed wants it
+func embedPath(objPath, outPath string) (string, error) {
+	outDir := "."
+	if outPath != "" {
+		outDir = filepath.Dir(outPath)
+	}
+	rel, err := filepath.Rel(outDir, objPath)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("can't embed %s: it must be in %s or below it", objPath, outDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func autoAttach(section string) bool {
+	for _, prefix := range autoAttachPrefixes {
+		if strings.HasPrefix(section, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// goName turns a C identifier into an exported Go one: "sys_enter__mmap"
+// becomes "SysEnterMmap"
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 || !unicode.IsLetter([]rune(b.String())[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// uniqueName makes sure two symbols of the same kind don't end up with the
+// same Go field name
+func uniqueName(seen map[string]bool, kind, name string) string {
+	unique := name
+	for i := 2; seen[kind+"."+unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	seen[kind+"."+unique] = true
+	return unique
+}
+
+var skeletonTemplate = template.Must(template.New("skeleton").Parse(`// Code generated by {{.Tool}} from {{.ObjFile}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .Embed}}
+	_ "embed"
+{{- end}}
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+{{if .Embed}}
+//go:embed {{.EmbedPath}}
+var {{.EmbedVar}} []byte
+{{end}}
+// {{.Name}} gives typed access to the maps, programs and global variables
+// of {{.ObjFile}}.
+type {{.Name}} struct {
+	Module *bpf.Module
+	Maps   {{.Name}}Maps
+	Progs  {{.Name}}Progs
+	Vars   {{.Name}}Vars
+	links  []*bpf.BPFLink
+}
+
+type {{.Name}}Maps struct {
+{{- range .Maps}}
+	{{.Field}} *bpf.BPFMap // {{.Name}}
+{{- end}}
+}
+
+type {{.Name}}Progs struct {
+{{- range .Progs}}
+	{{.Field}} *bpf.BPFProg // {{.Name}}
+{{- end}}
+}
+
+type {{.Name}}Vars struct {
+{{- range .Vars}}
+	{{.Field}} *bpf.GlobalVariable // {{.Name}}
+{{- end}}
+}
+
+// Open{{.Name}} opens {{.ObjFile}}. Maps, programs and global variables can
+// be configured before calling Load.
+func Open{{.Name}}(args bpf.NewModuleArgs) (*{{.Name}}, error) {
+{{- if .Embed}}
+	args.BPFObjBuff = {{.EmbedVar}}
+	if args.BPFObjName == "" {
+		args.BPFObjName = "{{.ObjName}}"
+	}
+	module, err := bpf.NewModuleFromBufferArgs(args)
+{{- else}}
+	if args.BPFObjPath == "" {
+		args.BPFObjPath = "{{.ObjFile}}"
+	}
+	module, err := bpf.NewModuleFromFileArgs(args)
+{{- end}}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &{{.Name}}{Module: module}
+	if err = s.resolve(); err != nil {
+		module.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *{{.Name}}) resolve() error {
+	var err error
+{{range .Maps}}
+	if s.Maps.{{.Field}}, err = s.Module.GetMap("{{.Name}}"); err != nil {
+		return err
+	}
+{{- end}}
+{{range .Progs}}
+	if s.Progs.{{.Field}}, err = s.Module.GetProgram("{{.Name}}"); err != nil {
+		return err
+	}
+{{- end}}
+{{range .Vars}}
+	if s.Vars.{{.Field}}, err = s.Module.GetGlobalVariable("{{.Name}}"); err != nil {
+		return err
+	}
+{{- end}}
+
+	return err
+}
+
+// Load loads the object into the kernel. Maps are resolved again, as their
+// file descriptors only exist once loaded.
+func (s *{{.Name}}) Load() error {
+	if err := s.Module.BPFLoadObject(); err != nil {
+		return err
+	}
+	return s.resolve()
+}
+
+// Attach attaches every program whose section tells libbpf where to attach
+// it. Other programs (e.g. XDP or TC) have to be attached by the caller.
+func (s *{{.Name}}) Attach() error {
+	progs := []*bpf.BPFProg{
+{{- range .AutoAttach}}
+		s.Progs.{{.Field}},
+{{- end}}
+	}
+	for _, prog := range progs {
+		link, err := prog.AttachGeneric()
+		if err != nil {
+			return err
+		}
+		s.links = append(s.links, link)
+	}
+	return nil
+}
+
+// Close detaches the programs attached by Attach and closes the module
+func (s *{{.Name}}) Close() {
+	for _, link := range s.links {
+		link.Destroy()
+	}
+	s.links = nil
+	s.Module.Close()
+}
+`))
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	testCases := map[string]string{
+		"events":               "Events",
+		"kprobe__sys_mmap":     "KprobeSysMmap",
+		"tracepoint__sys_dup2": "TracepointSysDup2",
+		"_private":             "Private",
+		"__1":                  "X1",
+	}
+	for name, expected := range testCases {
+		if got := goName(name); got != expected {
+			t.Errorf("goName(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, expected := range []string{"FooBar", "FooBar2", "FooBar3"} {
+		if got := uniqueName(seen, "Maps", "FooBar"); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+	if got := uniqueName(seen, "Progs", "FooBar"); got != "FooBar" {
+		t.Errorf("field names should only clash within the same kind, got %q", got)
+	}
+}
+
+func TestEmbedPath(t *testing.T) {
+	if p, err := embedPath("bpf/main.bpf.o", "skel.go"); err != nil || p != "bpf/main.bpf.o" {
+		t.Errorf("unexpected embed path %q (%v)", p, err)
+	}
+	if _, err := embedPath("../main.bpf.o", "skel.go"); err == nil {
+		t.Errorf("embedding an object outside the package must fail")
+	}
+}
+
+func TestSkeletonTemplate(t *testing.T) {
+	for _, embed := range []bool{false, true} {
+		skel := skeleton{
+			Tool:       "libbpfgo-skel",
+			ObjFile:    "main.bpf.o",
+			ObjName:    "main",
+			Package:    "main",
+			Name:       "Main",
+			Embed:      embed,
+			EmbedPath:  "main.bpf.o",
+			EmbedVar:   "mainObject",
+			Maps:       []symbol{{"events", "Events"}},
+			Progs:      []symbol{{"kprobe__sys_mmap", "KprobeSysMmap"}, {"xdp_drop", "XdpDrop"}},
+			AutoAttach: []symbol{{"kprobe__sys_mmap", "KprobeSysMmap"}},
+			Vars:       []symbol{{"cfg", "Cfg"}},
+		}
+
+		var buf bytes.Buffer
+		if err := skeletonTemplate.Execute(&buf, skel); err != nil {
+			t.Fatalf("failed to execute template: %v", err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			t.Fatalf("generated code does not parse: %v\n%s", err, buf.String())
+		}
+
+		for _, expected := range []string{
+			"Events *bpf.BPFMap",
+			"KprobeSysMmap *bpf.BPFProg",
+			"Cfg *bpf.GlobalVariable",
+			`s.Module.GetGlobalVariable("cfg")`,
+		} {
+			if !bytes.Contains(src, []byte(expected)) {
+				t.Errorf("generated code lacks %q", expected)
+			}
+		}
+		if strings.Contains(string(src), "s.Progs.XdpDrop,\n") {
+			t.Errorf("XDP program must not be attached automatically")
+		}
+		if embed != strings.Contains(string(src), "//go:embed main.bpf.o") {
+			t.Errorf("unexpected go:embed directive (embed=%v)", embed)
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package libbpfgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"unsafe"
+)
+
+// Map dumps start with a header, in little-endian:
+//
+//	magic       [4]byte "BPFD"
+//	version     uint16
+//	byte order  uint8   1 little-endian, 2 big-endian: of keys and values
+//	reserved    uint8
+//	type        uint32
+//	key size    uint32
+//	value size  uint32
+//	max entries uint32
+//	num CPUs    uint32  values per entry
+//	name length uint16
+//	name        [name length]byte
+//
+// followed by chunks of entries, each a uint32 count and count entries:
+// the key, then the value of each CPU, without padding. A chunk of 0
+// entries ends the dump.
+
+const (
+	DumpVersion = 1
+
+	dumpMagic        = "BPFD"
+	dumpLittleEndian = 1
+	dumpBigEndian    = 2
+	dumpChunkSize    = 1024
+
+	// bounds of the sizes in dump headers, not to allocate whatever a
+	// corrupt dump says
+	dumpMaxKeySize         = 512     // MAX_BPF_STACK, the largest hash key
+	dumpMaxValueSize       = 1 << 22 // KMALLOC_MAX_SIZE with 4K pages
+	dumpMaxPerCPUValueSize = 1 << 15 // PCPU_MIN_UNIT_SIZE
+	dumpMaxCPUs            = 8192    // largest NR_CPUS
+)
+
+// DumpHeader describes the map a dump was taken from
+type DumpHeader struct {
+	Version    uint16
+	Name       string
+	Type       MapType
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	NumCPUs    uint32 // values per entry: possible CPUs for per-CPU maps, 1 otherwise
+}
+
+func dumpByteOrder() uint8 {
+	if nativeEndian == binary.LittleEndian {
+		return dumpLittleEndian
+	}
+	return dumpBigEndian
+}
+
+func (b *BPFMap) dumpHeader() (*DumpHeader, error) {
+	switch t := b.Type(); t {
+	case MapTypeQueue, MapTypeStack, MapTypeBloomFilter, MapTypeRingbuf:
+		// no keys to walk the entries by
+		return nil, fmt.Errorf("unsupported map type %s: %w", t, syscall.EINVAL)
+	}
+
+	header := &DumpHeader{
+		Version:    DumpVersion,
+		Name:       b.name,
+		Type:       b.Type(),
+		KeySize:    uint32(b.KeySize()),
+		ValueSize:  uint32(b.ValueSize()),
+		MaxEntries: b.GetMaxEntries(),
+		NumCPUs:    1,
+	}
+	if isPerCPU(header.Type) {
+		size, err := b.perCPUSize()
+		if err != nil {
+			return nil, err
+		}
+		header.NumCPUs = uint32(size / b.perCPUValueSize())
+	}
+	return header, nil
+}
+
+// dumpWriter writes the header and entries of a dump
+type dumpWriter struct {
+	w     *bufio.Writer
+	chunk []byte
+	count uint32
+}
+
+func newDumpWriter(w io.Writer, header *DumpHeader) (*dumpWriter, error) {
+	var fixed [30]byte
+	copy(fixed[:], dumpMagic)
+	binary.LittleEndian.PutUint16(fixed[4:], header.Version)
+	fixed[6] = dumpByteOrder()
+	binary.LittleEndian.PutUint32(fixed[8:], uint32(header.Type))
+	binary.LittleEndian.PutUint32(fixed[12:], header.KeySize)
+	binary.LittleEndian.PutUint32(fixed[16:], header.ValueSize)
+	binary.LittleEndian.PutUint32(fixed[20:], header.MaxEntries)
+	binary.LittleEndian.PutUint32(fixed[24:], header.NumCPUs)
+	binary.LittleEndian.PutUint16(fixed[28:], uint16(len(header.Name)))
+
+	dw := &dumpWriter{w: bufio.NewWriter(w)}
+	dw.w.Write(fixed[:])
+	_, err := dw.w.WriteString(header.Name)
+	return dw, err
+}
+
+// writeEntry writes key and the value of each CPU
+func (dw *dumpWriter) writeEntry(key []byte, values ...[]byte) error {
+	dw.chunk = append(dw.chunk, key...)
+	for _, value := range values {
+		dw.chunk = append(dw.chunk, value...)
+	}
+	dw.count++
+	if dw.count == dumpChunkSize {
+		return dw.flush()
+	}
+	return nil
+}
+
+func (dw *dumpWriter) flush() error {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], dw.count)
+	dw.w.Write(n[:])
+	_, err := dw.w.Write(dw.chunk)
+	dw.chunk, dw.count = dw.chunk[:0], 0
+	return err
+}
+
+// close writes the last entries and the end of the dump
+func (dw *dumpWriter) close() error {
+	if dw.count > 0 {
+		if err := dw.flush(); err != nil {
+			return err
+		}
+	}
+	if err := dw.flush(); err != nil {
+		return err
+	}
+	return dw.w.Flush()
+}
+
+// Dump writes every entry of the map to w, in a format Restore reads back.
+// Entries updated while dumping may or may not be dumped, see
+// BPFMapEntryIterator.
+func (b *BPFMap) Dump(w io.Writer) error {
+	header, err := b.dumpHeader()
+	if err != nil {
+		return fmt.Errorf("failed to dump map %s: %w", b.name, err)
+	}
+	dw, err := newDumpWriter(w, header)
+	if err != nil {
+		return fmt.Errorf("failed to dump map %s: %w", b.name, err)
+	}
+
+	it := b.EntryIterator(dumpChunkSize)
+	for it.Next() {
+		if isPerCPU(header.Type) {
+			err = dw.writeEntry(it.Key(), it.ValuePerCPU()...)
+		} else {
+			err = dw.writeEntry(it.Key(), it.Value())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to dump map %s: %w", b.name, err)
+		}
+	}
+	if err = it.Err(); err != nil {
+		return fmt.Errorf("failed to dump map %s: %w", b.name, err)
+	}
+	if err = dw.close(); err != nil {
+		return fmt.Errorf("failed to dump map %s: %w", b.name, err)
+	}
+	return nil
+}
+
+// DumpReader reads the entries of a dump written by BPFMap.Dump
+type DumpReader struct {
+	r         *bufio.Reader
+	header    DumpHeader
+	remaining uint32 // entries left in the current chunk
+	entry     []byte
+	done      bool
+	err       error
+}
+
+// NewDumpReader reads the header of the dump
+func NewDumpReader(r io.Reader) (*DumpReader, error) {
+	return newDumpReader(r, nil)
+}
+
+// newDumpReader reads the header of a dump, and checks it with check, if
+// not nil, before allocating anything for its entries
+func newDumpReader(r io.Reader, check func(h *DumpHeader) error) (*DumpReader, error) {
+	d := &DumpReader{r: bufio.NewReader(r)}
+
+	var fixed [30]byte
+	if _, err := io.ReadFull(d.r, fixed[:]); err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+	if string(fixed[:4]) != dumpMagic {
+		return nil, fmt.Errorf("failed to read dump header: not a map dump")
+	}
+	h := &d.header
+	h.Version = binary.LittleEndian.Uint16(fixed[4:])
+	if h.Version == 0 || h.Version > DumpVersion {
+		return nil, fmt.Errorf("failed to read dump header: unsupported version %d", h.Version)
+	}
+	if fixed[6] != dumpByteOrder() {
+		return nil, fmt.Errorf("failed to read dump header: dump taken on a host of another byte order")
+	}
+	h.Type = MapType(binary.LittleEndian.Uint32(fixed[8:]))
+	h.KeySize = binary.LittleEndian.Uint32(fixed[12:])
+	h.ValueSize = binary.LittleEndian.Uint32(fixed[16:])
+	h.MaxEntries = binary.LittleEndian.Uint32(fixed[20:])
+	h.NumCPUs = binary.LittleEndian.Uint32(fixed[24:])
+	if err := checkDumpSizes(h); err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+
+	name := make([]byte, binary.LittleEndian.Uint16(fixed[28:]))
+	if _, err := io.ReadFull(d.r, name); err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+	h.Name = string(name)
+
+	if check != nil {
+		if err := check(h); err != nil {
+			return nil, err
+		}
+	}
+
+	// bounded by checkDumpSizes: no overflow
+	d.entry = make([]byte, int(h.KeySize)+int(h.NumCPUs)*int(h.ValueSize))
+	return d, nil
+}
+
+// checkDumpSizes bounds the key and value sizes and the number of CPUs of
+// a dump header to what the kernel allows
+func checkDumpSizes(h *DumpHeader) error {
+	maxValueSize := uint32(dumpMaxValueSize)
+	if isPerCPU(h.Type) {
+		maxValueSize = dumpMaxPerCPUValueSize
+	}
+
+	switch {
+	case h.KeySize == 0 || h.KeySize > dumpMaxKeySize:
+		return fmt.Errorf("invalid key size %d: %w", h.KeySize, syscall.EINVAL)
+	case h.ValueSize == 0 || h.ValueSize > maxValueSize:
+		return fmt.Errorf("invalid value size %d: %w", h.ValueSize, syscall.EINVAL)
+	case h.NumCPUs == 0 || h.NumCPUs > dumpMaxCPUs || (h.NumCPUs > 1 && !isPerCPU(h.Type)):
+		return fmt.Errorf("invalid number of CPUs %d: %w", h.NumCPUs, syscall.EINVAL)
+	}
+	return nil
+}
+
+func (d *DumpReader) Header() DumpHeader {
+	return d.header
+}
+
+// Next reads the next entry. It returns false at the end of the dump or on
+// error, see Err.
+func (d *DumpReader) Next() bool {
+	if d.done || d.err != nil {
+		return false
+	}
+
+	for d.remaining == 0 {
+		var n [4]byte
+		if _, err := io.ReadFull(d.r, n[:]); err != nil {
+			d.err = fmt.Errorf("failed to read dump: %w", unexpectedEOF(err))
+			return false
+		}
+		d.remaining = binary.LittleEndian.Uint32(n[:])
+		if d.remaining == 0 {
+			d.done = true
+			return false
+		}
+	}
+
+	if _, err := io.ReadFull(d.r, d.entry); err != nil {
+		d.err = fmt.Errorf("failed to read dump: %w", unexpectedEOF(err))
+		return false
+	}
+	d.remaining--
+	return true
+}
+
+// unexpectedEOF turns EOF into ErrUnexpectedEOF: a dump ends with an empty
+// chunk
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Key returns the key of the current entry. The slice is valid only until
+// the next call to Next.
+func (d *DumpReader) Key() []byte {
+	return d.entry[:d.header.KeySize]
+}
+
+// Value returns the value of the current entry, of CPU 0 for per-CPU maps.
+// The slice is valid only until the next call to Next.
+func (d *DumpReader) Value() []byte {
+	return d.entry[d.header.KeySize : d.header.KeySize+d.header.ValueSize]
+}
+
+// ValuePerCPU returns the values of the current entry, one per CPU. The
+// slices are valid only until the next call to Next.
+func (d *DumpReader) ValuePerCPU() [][]byte {
+	values := make([][]byte, d.header.NumCPUs)
+	for cpu := range values {
+		start := d.header.KeySize + uint32(cpu)*d.header.ValueSize
+		values[cpu] = d.entry[start : start+d.header.ValueSize]
+	}
+	return values
+}
+
+func (d *DumpReader) Err() error {
+	return d.err
+}
+
+// checkDumpHeader checks a dump can be restored into the map: of the same
+// type and sizes, with as many CPUs and at least as many max entries
+func (b *BPFMap) checkDumpHeader(dump *DumpHeader) error {
+	header, err := b.dumpHeader()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dump.Type != header.Type:
+		return fmt.Errorf("dump of a %s, map is a %s", dump.Type, header.Type)
+	case dump.KeySize != header.KeySize:
+		return fmt.Errorf("dump has key size %d, map %d", dump.KeySize, header.KeySize)
+	case dump.ValueSize != header.ValueSize:
+		return fmt.Errorf("dump has value size %d, map %d", dump.ValueSize, header.ValueSize)
+	case dump.NumCPUs != header.NumCPUs:
+		return fmt.Errorf("dump has values for %d CPUs, map %d", dump.NumCPUs, header.NumCPUs)
+	case dump.MaxEntries > header.MaxEntries:
+		return fmt.Errorf("dump has max entries %d, map %d", dump.MaxEntries, header.MaxEntries)
+	}
+	return nil
+}
+
+// Restore updates the map with the entries of a dump written by Dump,
+// refusing dumps of maps of another type, key or value size, number of
+// CPUs, or of more max entries. Entries are updated in batches, or one by
+// one on kernels and map types without batch operations. Entries of the
+// map not in the dump are left untouched.
+func (b *BPFMap) Restore(r io.Reader) error {
+	d, err := newDumpReader(r, func(h *DumpHeader) error {
+		if err := b.checkDumpHeader(h); err != nil {
+			return fmt.Errorf("%v: %w", err, syscall.EINVAL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore map %s: %w", b.name, err)
+	}
+	header := d.Header()
+
+	// values as the kernel lays them out, padded for per-CPU maps
+	stride := b.ValueSize()
+	if isPerCPU(header.Type) {
+		stride = b.perCPUValueSize() * int(header.NumCPUs)
+	}
+
+	var (
+		keys    = make([]byte, 0, dumpChunkSize*int(header.KeySize))
+		values  = make([]byte, 0, dumpChunkSize*stride)
+		count   uint32
+		noBatch bool
+	)
+	flush := func() error {
+		if count == 0 {
+			return nil
+		}
+		defer func() { keys, values, count = keys[:0], values[:0], 0 }()
+
+		if !noBatch {
+			err := b.UpdateBatch(unsafe.Pointer(&keys[0]), unsafe.Pointer(&values[0]), count)
+			if !errors.Is(err, syscall.EINVAL) && !errors.Is(err, errENOTSUPP) {
+				return err
+			}
+			noBatch = true
+		}
+		for i := 0; i < int(count); i++ {
+			key := keys[i*int(header.KeySize):]
+			value := values[i*stride:]
+			if err := b.Update(unsafe.Pointer(&key[0]), unsafe.Pointer(&value[0])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for d.Next() {
+		keys = append(keys, d.Key()...)
+		for _, value := range d.ValuePerCPU() {
+			values = append(values, value...)
+			if isPerCPU(header.Type) {
+				values = append(values, make([]byte, b.perCPUValueSize()-b.ValueSize())...)
+			}
+		}
+		count++
+		if count == dumpChunkSize {
+			if err = flush(); err != nil {
+				return fmt.Errorf("failed to restore map %s: %w", b.name, err)
+			}
+		}
+	}
+	if err = d.Err(); err != nil {
+		return fmt.Errorf("failed to restore map %s: %w", b.name, err)
+	}
+	if err = flush(); err != nil {
+		return fmt.Errorf("failed to restore map %s: %w", b.name, err)
+	}
+	return nil
+}
+
+type dumpJSONHeader struct {
+	Version    uint16 `json:"version"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	KeySize    uint32 `json:"key_size"`
+	ValueSize  uint32 `json:"value_size"`
+	MaxEntries uint32 `json:"max_entries"`
+	NumCPUs    uint32 `json:"num_cpus"`
+}
+
+type dumpJSONEntry struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"` // per-CPU maps
+}
+
+// DumpToJSON renders a dump written by BPFMap.Dump as JSON, keys and
+// values in hex:
+//
+//	{"header":{"version":1,"name":"counts",...},"entries":[{"key":"01000000","value":"2a00000000000000"},...]}
+//
+// Values of per-CPU maps are rendered as "values", one per CPU.
+func DumpToJSON(r io.Reader, w io.Writer) error {
+	d, err := NewDumpReader(r)
+	if err != nil {
+		return err
+	}
+	h := d.Header()
+
+	header, err := json.Marshal(dumpJSONHeader{
+		Version:    h.Version,
+		Name:       h.Name,
+		Type:       h.Type.String(),
+		KeySize:    h.KeySize,
+		ValueSize:  h.ValueSize,
+		MaxEntries: h.MaxEntries,
+		NumCPUs:    h.NumCPUs,
+	})
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `{"header":%s,"entries":[`, header)
+	for i := 0; d.Next(); i++ {
+		entry := dumpJSONEntry{Key: hex.EncodeToString(d.Key())}
+		if isPerCPU(h.Type) {
+			for _, value := range d.ValuePerCPU() {
+				entry.Values = append(entry.Values, hex.EncodeToString(value))
+			}
+		} else {
+			entry.Value = hex.EncodeToString(d.Value())
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.Write(data)
+	}
+	if err = d.Err(); err != nil {
+		return err
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
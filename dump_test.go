@@ -0,0 +1,182 @@
+package libbpfgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+)
+
+func writeTestDump(t *testing.T, header *DumpHeader, entries int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	dw, err := newDumpWriter(&buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		key := []byte{byte(i), byte(i >> 8), 0, 0}
+		var values [][]byte
+		for cpu := 0; cpu < int(header.NumCPUs); cpu++ {
+			values = append(values, []byte{byte(cpu), byte(i), 0, 0, 0, 0, 0, 0})
+		}
+		if err = dw.writeEntry(key, values...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = dw.close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	header := &DumpHeader{
+		Version:    DumpVersion,
+		Name:       "counts",
+		Type:       MapTypePerCPUHash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 4096,
+		NumCPUs:    3,
+	}
+	// more than a chunk
+	const entries = dumpChunkSize + 10
+	dump := writeTestDump(t, header, entries)
+
+	d, err := NewDumpReader(bytes.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Header() != *header {
+		t.Errorf("expected header %+v, got %+v", *header, d.Header())
+	}
+
+	n := 0
+	for ; d.Next(); n++ {
+		if !bytes.Equal(d.Key(), []byte{byte(n), byte(n >> 8), 0, 0}) {
+			t.Fatalf("entry %d: unexpected key %v", n, d.Key())
+		}
+		values := d.ValuePerCPU()
+		if len(values) != 3 || values[2][0] != 2 || values[2][1] != byte(n) {
+			t.Fatalf("entry %d: unexpected values %v", n, values)
+		}
+	}
+	if d.Err() != nil {
+		t.Fatal(d.Err())
+	}
+	if n != entries {
+		t.Errorf("expected %d entries, got %d", entries, n)
+	}
+}
+
+func TestDumpReaderErrors(t *testing.T) {
+	header := &DumpHeader{
+		Version:    DumpVersion,
+		Type:       MapTypeHash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 16,
+		NumCPUs:    1,
+	}
+	dump := writeTestDump(t, header, 2)
+
+	if _, err := NewDumpReader(bytes.NewReader([]byte("not a dump, but long enough to hold a header"))); err == nil {
+		t.Error("expected an error without magic")
+	}
+
+	future := append([]byte(nil), dump...)
+	future[4] = DumpVersion + 1
+	if _, err := NewDumpReader(bytes.NewReader(future)); err == nil {
+		t.Error("expected an error for a future version")
+	}
+
+	corrupt := func(off int, v uint32) []byte {
+		c := append([]byte(nil), dump...)
+		binary.LittleEndian.PutUint32(c[off:], v)
+		return c
+	}
+	for name, c := range map[string][]byte{
+		"no key":                    corrupt(12, 0),
+		"huge key":                  corrupt(12, 1<<20),
+		"no value":                  corrupt(16, 0),
+		"huge value":                corrupt(16, 1<<30),
+		"no CPUs":                   corrupt(24, 0),
+		"overflowing CPUs":          corrupt(24, 1<<31),
+		"CPUs of a non-per-CPU map": corrupt(24, 4),
+	} {
+		if _, err := NewDumpReader(bytes.NewReader(c)); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: expected EINVAL, got %v", name, err)
+		}
+	}
+
+	// without the empty chunk ending it
+	d, err := NewDumpReader(bytes.NewReader(dump[:len(dump)-4]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d.Next() {
+	}
+	if !errors.Is(d.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("expected an unexpected EOF, got %v", d.Err())
+	}
+}
+
+func TestDumpUnsupportedMapType(t *testing.T) {
+	header := &DumpHeader{
+		Version:    DumpVersion,
+		Type:       MapTypeHash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 16,
+		NumCPUs:    1,
+	}
+	dump := writeTestDump(t, header, 2)
+
+	for _, mapType := range []MapType{MapTypeQueue, MapTypeStack, MapTypeBloomFilter} {
+		bpfMap := &BPFMap{name: "events", fd: -1, info: &BPFMapInfo{Type: mapType, ValueSize: 8, MaxEntries: 16}}
+
+		if err := bpfMap.Dump(io.Discard); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: Dump: expected EINVAL, got %v", mapType, err)
+		}
+		if err := bpfMap.Restore(bytes.NewReader(dump)); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: Restore: expected EINVAL, got %v", mapType, err)
+		}
+	}
+}
+
+func TestDumpToJSON(t *testing.T) {
+	header := &DumpHeader{
+		Version:    DumpVersion,
+		Name:       "counts",
+		Type:       MapTypeHash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 16,
+		NumCPUs:    1,
+	}
+	dump := writeTestDump(t, header, 2)
+
+	var out bytes.Buffer
+	if err := DumpToJSON(bytes.NewReader(dump), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var rendered struct {
+		Header  dumpJSONHeader  `json:"header"`
+		Entries []dumpJSONEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &rendered); err != nil {
+		t.Fatalf("invalid JSON %s: %v", out.String(), err)
+	}
+	if rendered.Header.Name != "counts" || rendered.Header.Type != "BPF_MAP_TYPE_HASH" {
+		t.Errorf("unexpected header %+v", rendered.Header)
+	}
+	if len(rendered.Entries) != 2 || rendered.Entries[1].Key != "01000000" || rendered.Entries[1].Value != "0001000000000000" {
+		t.Errorf("unexpected entries %+v", rendered.Entries)
+	}
+}
@@ -0,0 +1,390 @@
+package libbpfgo
+
+/*
+#include <errno.h>
+#include <stdlib.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#include <bpf/bpf.h>
+#include <bpf/btf.h>
+#include <bpf/libbpf.h>
+
+static __u32 btf_type_type(const struct btf_type *t)
+{
+    return t->type;
+}
+
+static const struct btf_var_secinfo *btf_datasec_var(const struct btf_type *t, int i)
+{
+    return btf_var_secinfos(t) + i;
+}
+
+// mmap_global_data maps the kernel memory of an internal map (.data, .bss,
+// .rodata) over the memory libbpf allocated for its initial value, the way
+// bpf_object__load_skeleton() does.
+static int mmap_global_data(struct bpf_map *map)
+{
+    size_t size, page_sz = sysconf(_SC_PAGE_SIZE);
+    void *mmaped, *remapped;
+    int prot = PROT_READ;
+
+    mmaped = (void *) bpf_map__initial_value(map, &size);
+    if (!mmaped || !(bpf_map__map_flags(map) & BPF_F_MMAPABLE))
+        return 0;
+
+    if (!(bpf_map__map_flags(map) & BPF_F_RDONLY_PROG))
+        prot |= PROT_WRITE;
+
+    size = (size + 7) / 8 * 8;
+    size = (size + page_sz - 1) / page_sz * page_sz;
+
+    remapped = mmap(mmaped, size, prot, MAP_SHARED | MAP_FIXED, bpf_map__fd(map), 0);
+    if (remapped == MAP_FAILED)
+        return -errno;
+
+    return 0;
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// GlobalVariable is a BPF global variable, living in one of the .rodata,
+// .data or .bss sections of the BPF object.
+type GlobalVariable struct {
+	name    string
+	section string
+	offset  int
+	size    int
+	kind    uint32 // BTF kind of the variable type
+	bpfMap  *C.struct_bpf_map
+	module  *Module
+}
+
+// InitGlobalVariable sets the initial value of the global variable name.
+// The value must be a fixed-size value (see encoding/binary) whose size and
+// kind match the BTF type of the variable; a []byte of the right size is
+// always accepted. It must be called prior to BPFLoadObject for variables
+// in .rodata, as those become read-only once loaded.
+func (m *Module) InitGlobalVariable(name string, value interface{}) error {
+	v, err := m.GetGlobalVariable(name)
+	if err != nil {
+		return err
+	}
+	return v.Set(value)
+}
+
+// GetGlobalVariable looks the global variable name up in the BTF DATASEC
+// information of the BPF object.
+func (m *Module) GetGlobalVariable(name string) (*GlobalVariable, error) {
+	var found *GlobalVariable
+	err := m.forEachGlobalVariable(func(v *GlobalVariable) bool {
+		if v.name == name {
+			found = v
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find global variable %s: %w", name, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("failed to find global variable %s: %w", name, syscall.ENOENT)
+	}
+	return found, nil
+}
+
+// GetGlobalVariables returns all the global variables of the BPF object
+func (m *Module) GetGlobalVariables() ([]*GlobalVariable, error) {
+	var vars []*GlobalVariable
+	err := m.forEachGlobalVariable(func(v *GlobalVariable) bool {
+		vars = append(vars, v)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list global variables: %w", err)
+	}
+	return vars, nil
+}
+
+// forEachGlobalVariable calls fn for every variable described in the
+// DATASECs of the object BTF, until fn returns false.
+func (m *Module) forEachGlobalVariable(fn func(v *GlobalVariable) bool) error {
+	btf := C.bpf_object__btf(m.obj)
+	if btf == nil {
+		return fmt.Errorf("BPF object has no BTF: %w", syscall.ENOENT)
+	}
+
+	it := m.Iterator()
+	for bpfMap := it.NextMap(); bpfMap != nil; bpfMap = it.NextMap() {
+		if !C.bpf_map__is_internal(bpfMap.bpfMap) {
+			continue
+		}
+		section := globalDataSection(bpfMap.name)
+		if section == "" {
+			continue
+		}
+
+		cs := C.CString(section)
+		secID := C.btf__find_by_name_kind(btf, cs, C.BTF_KIND_DATASEC)
+		C.free(unsafe.Pointer(cs))
+		if secID < 0 {
+			continue
+		}
+
+		sec := C.btf__type_by_id(btf, C.uint(secID))
+		for j := 0; j < int(C.btf_vlen(sec)); j++ {
+			secinfo := C.btf_datasec_var(sec, C.int(j))
+			varType := C.btf__type_by_id(btf, secinfo._type)
+			if varType == nil || C.btf_kind(varType) != C.BTF_KIND_VAR {
+				continue
+			}
+			name := C.GoString(C.btf__name_by_offset(btf, varType.name_off))
+
+			typeID := C.btf__resolve_type(btf, C.btf_type_type(varType))
+			if typeID < 0 {
+				return fmt.Errorf("failed to resolve type of %s: %w", name, syscall.Errno(-typeID))
+			}
+
+			v := &GlobalVariable{
+				name:    name,
+				section: section,
+				offset:  int(secinfo.offset),
+				size:    int(secinfo.size),
+				kind:    uint32(C.btf_kind(C.btf__type_by_id(btf, C.uint(typeID)))),
+				bpfMap:  bpfMap.bpfMap,
+				module:  m,
+			}
+			if !fn(v) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// globalDataSections are the sections libbpf names internal maps after,
+// prefixed with the object name
+var globalDataSections = []string{".bss", ".data", ".rodata", ".kconfig"}
+
+// globalDataSection returns the ELF section of the internal map name. Maps
+// of the standard sections are named after the object and the section (e.g.
+// "main.bss", or "main.bpf.bss" for an object named "main.bpf.o"), maps of
+// custom sections after the section alone (e.g. ".data.counters").
+func globalDataSection(name string) string {
+	if strings.HasPrefix(name, ".") {
+		return name
+	}
+	for _, section := range globalDataSections {
+		if strings.HasSuffix(name, section) {
+			return section
+		}
+	}
+	return ""
+}
+
+func (v *GlobalVariable) Name() string {
+	return v.name
+}
+
+// Section returns the name of the ELF section the variable lives in
+func (v *GlobalVariable) Section() string {
+	return v.section
+}
+
+func (v *GlobalVariable) Size() int {
+	return v.size
+}
+
+// Bytes returns the memory of the variable. Before the module is loaded
+// it holds the initial value. After load, for maps the kernel allows to be
+// memory-mapped, it is a live view of the kernel memory: writes to .data
+// and .bss variables are seen by the BPF programs right away. Otherwise it
+// is only refreshed by Read. Variables in .rodata are read-only once loaded
+// and must not be written to.
+func (v *GlobalVariable) Bytes() []byte {
+	v.mmaped()
+	data := v.sectionData()
+	if data == nil {
+		return nil
+	}
+	return data[v.offset : v.offset+v.size]
+}
+
+// sectionData returns the memory of the whole section of the variable
+func (v *GlobalVariable) sectionData() []byte {
+	var size C.size_t
+	ptr := C.bpf_map__initial_value(v.bpfMap, &size)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(ptr), int(size))
+}
+
+// Read decodes the current value of the variable into value, which must
+// be a pointer to a fixed-size value matching the variable type.
+func (v *GlobalVariable) Read(value interface{}) error {
+	if err := v.checkValue(value); err != nil {
+		return err
+	}
+	if err := v.sync(); err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(v.Bytes()), nativeEndian, value)
+}
+
+// Set writes value into the variable (see InitGlobalVariable)
+func (v *GlobalVariable) Set(value interface{}) error {
+	if err := v.checkValue(value); err != nil {
+		return err
+	}
+	if v.module.loaded && C.bpf_map__map_flags(v.bpfMap)&C.BPF_F_RDONLY_PROG != 0 {
+		return fmt.Errorf("failed to set global variable %s: %s is read-only once loaded: %w", v.name, v.section, syscall.EPERM)
+	}
+	// mmap before writing, not to write to memory the mapping replaces
+	mmaped := v.mmaped()
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, nativeEndian, value); err != nil {
+		return fmt.Errorf("failed to encode global variable %s: %w", v.name, err)
+	}
+	data := v.sectionData()
+	if data == nil {
+		return fmt.Errorf("failed to set global variable %s: no memory for section %s", v.name, v.section)
+	}
+
+	if !v.module.loaded || mmaped {
+		copy(data[v.offset:v.offset+v.size], buf.Bytes())
+		return nil
+	}
+	// the map can't be mmaped: push the whole section through it
+	err := v.writeSection(data, buf.Bytes(), v.lookupSection, v.updateSection)
+	if err != nil {
+		return fmt.Errorf("failed to update global variable %s: %w", v.name, err)
+	}
+	return nil
+}
+
+// writeSection writes value over the variable in data, the memory of its
+// whole section, between a lookup and an update of the section: the other
+// variables of the section keep the values the BPF programs gave them.
+func (v *GlobalVariable) writeSection(data, value []byte, lookup, update func(data []byte) error) error {
+	if err := lookup(data); err != nil {
+		return err
+	}
+	copy(data[v.offset:v.offset+v.size], value)
+	return update(data)
+}
+
+// lookupSection reads the section of the variable from the kernel into data
+func (v *GlobalVariable) lookupSection(data []byte) error {
+	key := C.int(0)
+	errC := C.bpf_map_lookup_elem(C.bpf_map__fd(v.bpfMap), unsafe.Pointer(&key), unsafe.Pointer(&data[0]))
+	if errC != 0 {
+		return syscall.Errno(-errC)
+	}
+	return nil
+}
+
+// updateSection writes data to the section of the variable in the kernel
+func (v *GlobalVariable) updateSection(data []byte) error {
+	key := C.int(0)
+	errC := C.bpf_map_update_elem(C.bpf_map__fd(v.bpfMap), unsafe.Pointer(&key), unsafe.Pointer(&data[0]), C.BPF_ANY)
+	if errC != 0 {
+		return syscall.Errno(-errC)
+	}
+	return nil
+}
+
+// mmaped tells whether the memory of the variable is the kernel memory. The
+// map is memory-mapped on the first access after load, so that loading does
+// not depend on it: if it can't be (old kernels, mmap failure), accesses
+// fall back to lookups and updates of the map.
+func (v *GlobalVariable) mmaped() bool {
+	m := v.module
+	if !m.loaded || C.bpf_map__map_flags(v.bpfMap)&C.BPF_F_MMAPABLE == 0 {
+		return false
+	}
+
+	mmaped, tried := m.globalData[v.bpfMap]
+	if !tried {
+		if m.globalData == nil {
+			m.globalData = make(map[*C.struct_bpf_map]bool)
+		}
+		mmaped = C.mmap_global_data(v.bpfMap) == 0
+		m.globalData[v.bpfMap] = mmaped
+	}
+	return mmaped
+}
+
+// sync refreshes the variable memory from the kernel when the map is not
+// memory-mapped
+func (v *GlobalVariable) sync() error {
+	if !v.module.loaded || v.mmaped() {
+		return nil
+	}
+	data := v.sectionData()
+	if data == nil {
+		return fmt.Errorf("failed to read global variable %s: no memory for section %s", v.name, v.section)
+	}
+	if err := v.lookupSection(data); err != nil {
+		return fmt.Errorf("failed to read global variable %s: %w", v.name, err)
+	}
+	return nil
+}
+
+// checkValue validates a Go value against the BTF type of the variable
+func (v *GlobalVariable) checkValue(value interface{}) error {
+	size := binary.Size(value)
+	if size < 0 {
+		return fmt.Errorf("invalid value for global variable %s: %T is not a fixed-size type", v.name, value)
+	}
+	if size != v.size {
+		return fmt.Errorf("invalid value for global variable %s: size of %T is %d, expected %d", v.name, value, size, v.size)
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(value))
+	kind := val.Kind()
+	if (kind == reflect.Array || kind == reflect.Slice) && val.Type().Elem().Kind() == reflect.Uint8 {
+		return nil // raw bytes
+	}
+
+	var ok bool
+	switch v.kind {
+	case C.BTF_KIND_INT, C.BTF_KIND_ENUM, C.BTF_KIND_ENUM64, C.BTF_KIND_PTR:
+		switch kind {
+		case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			ok = true
+		}
+	case C.BTF_KIND_FLOAT:
+		ok = kind == reflect.Float32 || kind == reflect.Float64
+	case C.BTF_KIND_STRUCT, C.BTF_KIND_UNION:
+		ok = kind == reflect.Struct
+	case C.BTF_KIND_ARRAY:
+		ok = kind == reflect.Array || kind == reflect.Slice
+	}
+	if !ok {
+		return fmt.Errorf("invalid value for global variable %s: %T does not match its BTF kind %d", v.name, value, v.kind)
+	}
+	return nil
+}
+
+// nativeEndian is the byte order BPF maps use to store values
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
@@ -0,0 +1,47 @@
+package libbpfgo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGlobalDataSection(t *testing.T) {
+	for name, section := range map[string]string{
+		"main.bss":       ".bss",
+		"main.bpf.bss":   ".bss",
+		"main.bpf.data":  ".data",
+		"my.obj.rodata":  ".rodata",
+		"main.kconfig":   ".kconfig",
+		".data.counters": ".data.counters",
+		"events":         "",
+	} {
+		if got := globalDataSection(name); got != section {
+			t.Errorf("globalDataSection(%q) = %q, expected %q", name, got, section)
+		}
+	}
+}
+
+func TestGlobalVariableWriteSection(t *testing.T) {
+	// two u64 variables in a section, "b" being set from userspace
+	v := &GlobalVariable{name: "b", section: ".data", offset: 8, size: 8}
+	kernel := []byte{1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2}
+	data := make([]byte, len(kernel)) // stale userspace copy
+
+	lookup := func(data []byte) error {
+		copy(data, kernel)
+		return nil
+	}
+	update := func(data []byte) error {
+		copy(kernel, data)
+		return nil
+	}
+	err := v.writeSection(data, []byte{3, 3, 3, 3, 3, 3, 3, 3}, lookup, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{1, 1, 1, 1, 1, 1, 1, 1, 3, 3, 3, 3, 3, 3, 3, 3}
+	if !bytes.Equal(kernel, expected) {
+		t.Errorf("section written as %v, expected %v", kernel, expected)
+	}
+}
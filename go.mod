@@ -4,6 +4,7 @@ go 1.18
 
 require (
 	github.com/stretchr/testify v1.7.0
+	github.com/ulikunitz/xz v0.5.10
 	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015
 )
 
@@ -1 +1,218 @@
 package helpers
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// ErrBTFNotFound is returned when the BTFHub archive has no BTF file for
+// the running kernel
+var ErrBTFNotFound = errors.New("BTF not found in BTFHub archive")
+
+// BTFHubFetcher gives access to the files of a BTFHub archive. name is a
+// slash separated path relative to the archive root, as returned by
+// BTFHubArchivePath. Fetch must return an error wrapping ErrBTFNotFound if
+// the file does not exist.
+type BTFHubFetcher interface {
+	Fetch(name string) (io.ReadCloser, error)
+}
+
+// BTFHubDir fetches BTF files from a local directory laid out like the
+// BTFHub archive repository
+type BTFHubDir string
+
+func (d BTFHubDir) Fetch(name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", name, ErrBTFNotFound)
+	}
+	return file, err
+}
+
+// BTFHubHTTP fetches BTF files from a server exposing the BTFHub archive
+// layout under BaseURL
+type BTFHubHTTP struct {
+	BaseURL string
+	Client  *http.Client // http.DefaultClient if nil
+}
+
+func (h *BTFHubHTTP) Fetch(name string) (io.ReadCloser, error) {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := strings.TrimSuffix(h.BaseURL, "/") + "/" + name
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %w", url, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", url, ErrBTFNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not fetch %s: %s", url, resp.Status)
+	}
+}
+
+// BTFHub resolves the external BTF file of the running kernel, for kernels
+// that do not ship /sys/kernel/btf/vmlinux (see OSBTFEnabled).
+//
+// Files are extracted to CacheDir, mirroring the archive layout, and are
+// only fetched again when missing from it.
+type BTFHub struct {
+	Fetcher  BTFHubFetcher
+	CacheDir string
+}
+
+// NewBTFHub creates a BTFHub fetching from fetcher and extracting to cacheDir
+func NewBTFHub(fetcher BTFHubFetcher, cacheDir string) *BTFHub {
+	return &BTFHub{
+		Fetcher:  fetcher,
+		CacheDir: cacheDir,
+	}
+}
+
+// BTFHubRelease identifies the BTF file of a kernel in the BTFHub archive
+type BTFHubRelease struct {
+	ID            string // os-release ID, e.g. ubuntu, possibly quoted
+	VersionID     string // os-release VERSION_ID, e.g. 20.04, possibly quoted
+	Arch          string // uname machine
+	KernelRelease string // uname release
+}
+
+// BTFHubReleaseFromOSInfo returns the release of the OS described by info
+func BTFHubReleaseFromOSInfo(info *OSInfo) BTFHubRelease {
+	return BTFHubRelease{
+		ID:            info.GetOSReleaseFieldValue(OS_ID),
+		VersionID:     info.GetOSReleaseFieldValue(OS_VERSION_ID),
+		Arch:          info.GetOSReleaseFieldValue(OS_ARCH),
+		KernelRelease: info.GetOSReleaseFieldValue(OS_KERNEL_RELEASE),
+	}
+}
+
+// BTFHubArchivePath returns the path of the BTF archive of the given release
+// within the BTFHub archive: <id>/<version>/<arch>/<release>.btf.tar.xz
+func BTFHubArchivePath(r BTFHubRelease) (string, error) {
+	fields := []struct {
+		field OSReleaseField
+		value string
+	}{
+		{OS_ID, r.ID},
+		{OS_VERSION_ID, r.VersionID},
+		{OS_ARCH, r.Arch},
+		{OS_KERNEL_RELEASE, r.KernelRelease},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			return "", fmt.Errorf("could not determine BTF archive path: %s is unknown", f.field)
+		}
+	}
+
+	// BTFHub follows the Go/Debian naming for arm64
+	arch := r.Arch
+	if arch == "aarch64" {
+		arch = "arm64"
+	}
+
+	return path.Join(unquote(r.ID), unquote(r.VersionID), arch, r.KernelRelease+".btf.tar.xz"), nil
+}
+
+// BTFPath returns the path of the BTF file of the given release, fetching
+// and extracting it to the cache directory if needed. The path can be given
+// to NewModuleArgs.BTFObjPath. If the archive has no BTF for this release,
+// the error wraps ErrBTFNotFound.
+func (h *BTFHub) BTFPath(r BTFHubRelease) (string, error) {
+	archivePath, err := BTFHubArchivePath(r)
+	if err != nil {
+		return "", err
+	}
+
+	btfPath := filepath.Join(h.CacheDir, filepath.FromSlash(strings.TrimSuffix(archivePath, ".tar.xz")))
+	if _, err = os.Stat(btfPath); err == nil {
+		return btfPath, nil
+	}
+
+	archive, err := h.Fetcher.Fetch(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	if err = extractBTF(archive, filepath.Base(btfPath), btfPath); err != nil {
+		return "", fmt.Errorf("could not extract %s: %w", archivePath, err)
+	}
+
+	return btfPath, nil
+}
+
+// GetBTFPath resolves the BTF file of the running kernel
+func (h *BTFHub) GetBTFPath() (string, error) {
+	info, err := GetOSInfo()
+	if err != nil {
+		return "", err
+	}
+	return h.BTFPath(BTFHubReleaseFromOSInfo(info))
+}
+
+// extractBTF extracts the file name from a .tar.xz archive to dest
+func extractBTF(archive io.Reader, name, dest string) error {
+	xzReader, err := xz.NewReader(archive)
+	if err != nil {
+		return err
+	}
+
+	tarReader := tar.NewReader(xzReader)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not in archive: %w", name, ErrBTFNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == name {
+			break
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	// extract next to dest and rename, so a partial file is never used
+	tmp, err := os.CreateTemp(filepath.Dir(dest), name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, tarReader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
+
+// unquote strips the quotes os-release values may be written with
+func unquote(s string) string {
+	return strings.Trim(s, `"'`)
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// writeBTFArchive creates a BTFHub-like <release>.btf.tar.xz under root
+func writeBTFArchive(t *testing.T, root, archivePath, release string, btf []byte) {
+	var buf bytes.Buffer
+	xzWriter, err := xz.NewWriter(&buf)
+	require.NoError(t, err)
+	tarWriter := tar.NewWriter(xzWriter)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name:     "./" + release + ".btf",
+		Mode:     0644,
+		Size:     int64(len(btf)),
+		Typeflag: tar.TypeReg,
+	}))
+	_, err = tarWriter.Write(btf)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, xzWriter.Close())
+
+	dest := filepath.Join(root, filepath.FromSlash(archivePath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(dest), 0755))
+	require.NoError(t, os.WriteFile(dest, buf.Bytes(), 0644))
+}
+
+func TestArchivePath(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		release      BTFHubRelease
+		expectedPath string
+		expectedErr  bool
+	}{
+		{
+			testName:     "ubuntu quoted version",
+			release:      BTFHubRelease{ID: "ubuntu", VersionID: `"20.04"`, Arch: "x86_64", KernelRelease: "5.4.0-1000-aws"},
+			expectedPath: "ubuntu/20.04/x86_64/5.4.0-1000-aws.btf.tar.xz",
+		},
+		{
+			testName:     "centos arm64",
+			release:      BTFHubRelease{ID: `"centos"`, VersionID: `"8"`, Arch: "aarch64", KernelRelease: "4.18.0-193.el8.aarch64"},
+			expectedPath: "centos/8/arm64/4.18.0-193.el8.aarch64.btf.tar.xz",
+		},
+		{
+			testName:    "missing version",
+			release:     BTFHubRelease{ID: "arch", Arch: "x86_64", KernelRelease: "5.18.1-arch1-1"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			p, err := BTFHubArchivePath(tc.release)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, p)
+		})
+	}
+}
+
+func TestFetchers(t *testing.T) {
+	archiveDir := t.TempDir()
+	release := BTFHubRelease{ID: "ubuntu", VersionID: `"20.04"`, Arch: "x86_64", KernelRelease: "5.4.0-1000-aws"}
+	archivePath, err := BTFHubArchivePath(release)
+	require.NoError(t, err)
+	btf := []byte("fake BTF blob")
+	writeBTFArchive(t, archiveDir, archivePath, "5.4.0-1000-aws", btf)
+
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	defer server.Close()
+
+	fetchers := map[string]BTFHubFetcher{
+		"dir":  BTFHubDir(archiveDir),
+		"http": &BTFHubHTTP{BaseURL: server.URL},
+	}
+
+	for name, fetcher := range fetchers {
+		t.Run(name, func(t *testing.T) {
+			hub := NewBTFHub(fetcher, t.TempDir())
+
+			btfPath, err := hub.BTFPath(release)
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(hub.CacheDir, "ubuntu/20.04/x86_64/5.4.0-1000-aws.btf"), btfPath)
+
+			content, err := os.ReadFile(btfPath)
+			require.NoError(t, err)
+			assert.Equal(t, btf, content)
+
+			// a cached file is not fetched again
+			hub.Fetcher = BTFHubDir(t.TempDir())
+			cachedPath, err := hub.BTFPath(release)
+			assert.NoError(t, err)
+			assert.Equal(t, btfPath, cachedPath)
+
+			// unknown kernel
+			_, err = hub.BTFPath(BTFHubRelease{ID: "ubuntu", VersionID: "20.04", Arch: "x86_64", KernelRelease: "5.4.0-0-unknown"})
+			assert.True(t, errors.Is(err, ErrBTFNotFound), "unexpected error: %v", err)
+
+			hub.Fetcher = fetcher
+			_, err = hub.BTFPath(BTFHubRelease{ID: "debian", VersionID: "11", Arch: "x86_64", KernelRelease: "5.10.0-13-amd64"})
+			assert.True(t, errors.Is(err, ErrBTFNotFound), "unexpected error: %v", err)
+		})
+	}
+}
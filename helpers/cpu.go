@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const possibleCPUsPath = "/sys/devices/system/cpu/possible"
+
+var (
+	possibleCPUs     int
+	possibleCPUsErr  error
+	possibleCPUsOnce sync.Once
+)
+
+// NumPossibleCPUs returns the number of possible CPUs, as read from
+// /sys/devices/system/cpu/possible. It is the number of values of per-CPU
+// maps, which may be more than the number of online CPUs.
+func NumPossibleCPUs() (int, error) {
+	possibleCPUsOnce.Do(func() {
+		data, err := os.ReadFile(possibleCPUsPath)
+		if err != nil {
+			possibleCPUsErr = err
+			return
+		}
+		cpus, err := ParseCPUList(string(data))
+		if err != nil {
+			possibleCPUsErr = fmt.Errorf("failed to parse %s: %w", possibleCPUsPath, err)
+			return
+		}
+		// CPUs are numbered from 0, per-CPU values are indexed by number
+		possibleCPUs = cpus[len(cpus)-1] + 1
+	})
+	return possibleCPUs, possibleCPUsErr
+}
+
+// ParseCPUList parses a list of CPUs in the format of the files of
+// /sys/devices/system/cpu, e.g. "0-3,6,8-9", into the sorted CPU numbers
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, fmt.Errorf("empty CPU list")
+	}
+	for _, part := range strings.Split(list, ",") {
+		first, last := part, part
+		if i := strings.IndexByte(part, '-'); i >= 0 {
+			first, last = part[:i], part[i+1:]
+		}
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q in %q", first, list)
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q in %q", last, list)
+		}
+		if from > to || (len(cpus) > 0 && from <= cpus[len(cpus)-1]) {
+			return nil, fmt.Errorf("invalid CPU range %q in %q", part, list)
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	testCases := []struct {
+		testName string
+		list     string
+		expected []int
+		fail     bool
+	}{
+		{testName: "single", list: "0\n", expected: []int{0}},
+		{testName: "range", list: "0-3", expected: []int{0, 1, 2, 3}},
+		{testName: "mixed", list: "0-1,4,6-7", expected: []int{0, 1, 4, 6, 7}},
+		{testName: "empty", list: "\n", fail: true},
+		{testName: "not a number", list: "0-a", fail: true},
+		{testName: "reversed range", list: "3-1", fail: true},
+		{testName: "unsorted", list: "4,0-1", fail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			cpus, err := ParseCPUList(tc.list)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cpus)
+		})
+	}
+}
+
+func TestNumPossibleCPUs(t *testing.T) {
+	n, err := NumPossibleCPUs()
+	if err != nil {
+		t.Skipf("no possible CPUs: %v", err)
+	}
+	assert.Greater(t, n, 0)
+}
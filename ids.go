@@ -0,0 +1,389 @@
+package libbpfgo
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+
+#include <bpf/bpf.h>
+
+static int get_link_info(int fd, struct bpf_link_info *info)
+{
+    __u32 len = sizeof(*info);
+
+    memset(info, 0, len);
+
+    return bpf_obj_get_info_by_fd(fd, info, &len);
+}
+
+static int get_btf_info(int fd, struct bpf_btf_info *info, void *btf, __u32 btf_size,
+                        char *name, __u32 name_len)
+{
+    __u32 len = sizeof(*info);
+
+    memset(info, 0, len);
+    info->btf = (__u64) (unsigned long) btf;
+    info->btf_size = btf_size;
+    info->name = (__u64) (unsigned long) name;
+    info->name_len = name_len;
+
+    return bpf_obj_get_info_by_fd(fd, info, &len);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/aquasecurity/libbpfgo/btf"
+)
+
+// BPFObjType is the type of the objects an IDIterator walks
+type BPFObjType uint32
+
+const (
+	BPFObjTypeProg BPFObjType = iota
+	BPFObjTypeMap
+	BPFObjTypeLink
+	BPFObjTypeBTF
+)
+
+func (t BPFObjType) String() string {
+	x := map[BPFObjType]string{
+		BPFObjTypeProg: "program",
+		BPFObjTypeMap:  "map",
+		BPFObjTypeLink: "link",
+		BPFObjTypeBTF:  "BTF",
+	}
+	str, ok := x[t]
+	if !ok {
+		str = fmt.Sprintf("object type %d", uint32(t))
+	}
+	return str
+}
+
+// IDIterator walks the IDs of all the objects of a type loaded on the
+// host, not only those of a module. Objects may go away between Next and
+// their opening by ID, which then fails with ENOENT.
+type IDIterator struct {
+	objType BPFObjType
+	id      uint32
+	err     error
+}
+
+func NewProgIDIterator() *IDIterator {
+	return &IDIterator{objType: BPFObjTypeProg}
+}
+
+func NewMapIDIterator() *IDIterator {
+	return &IDIterator{objType: BPFObjTypeMap}
+}
+
+func NewLinkIDIterator() *IDIterator {
+	return &IDIterator{objType: BPFObjTypeLink}
+}
+
+func NewBTFIDIterator() *IDIterator {
+	return &IDIterator{objType: BPFObjTypeBTF}
+}
+
+func (it *IDIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	var next C.__u32
+	var errC C.int
+	switch it.objType {
+	case BPFObjTypeProg:
+		errC = C.bpf_prog_get_next_id(C.__u32(it.id), &next)
+	case BPFObjTypeMap:
+		errC = C.bpf_map_get_next_id(C.__u32(it.id), &next)
+	case BPFObjTypeLink:
+		errC = C.bpf_link_get_next_id(C.__u32(it.id), &next)
+	case BPFObjTypeBTF:
+		errC = C.bpf_btf_get_next_id(C.__u32(it.id), &next)
+	default:
+		it.err = fmt.Errorf("unknown %s", it.objType)
+		return false
+	}
+	if errC < 0 {
+		if syscall.Errno(-errC) != syscall.ENOENT {
+			it.err = fmt.Errorf("failed to get next %s id after %d: %w", it.objType, it.id, syscall.Errno(-errC))
+		}
+		return false
+	}
+
+	it.id = uint32(next)
+	return true
+}
+
+// ID returns the current ID, if the most recent call to Next returned true
+func (it *IDIterator) ID() uint32 {
+	return it.id
+}
+
+// Err returns the last error that occurred while iterating
+func (it *IDIterator) Err() error {
+	return it.err
+}
+
+// NewBPFProgFromID opens the loaded program id. The program is not part of
+// any module: it only supports the methods working on its fd (GetFd,
+// GetType, Info, TestRun, ...), and Close must be called to release it.
+func NewBPFProgFromID(id uint32) (*BPFProg, error) {
+	fd := C.bpf_prog_get_fd_by_id(C.__u32(id))
+	if fd < 0 {
+		return nil, fmt.Errorf("failed to open program %d: %w", id, syscall.Errno(-fd))
+	}
+	return newBPFProgFromFD(fd)
+}
+
+func newBPFProgFromFD(fd C.int) (*BPFProg, error) {
+	info, err := progInfoByFD(int(fd))
+	if err != nil {
+		C.close(fd)
+		return nil, fmt.Errorf("failed to get info of program: %w", err)
+	}
+	return &BPFProg{
+		name: info.Name,
+		fd:   fd,
+		info: info,
+	}, nil
+}
+
+// Close releases a program opened outside of a module. Programs of a
+// module are released by Module.Close.
+func (p *BPFProg) Close() error {
+	if p.prog != nil || p.fd < 0 {
+		return nil
+	}
+	if ret, errno := C.close(p.fd); ret < 0 {
+		return fmt.Errorf("failed to close program %s: %w", p.name, errno)
+	}
+	p.fd = -1
+	return nil
+}
+
+// NewBPFMapFromID opens the map id. The map is not part of any module: it
+// supports the methods working on its fd (GetValue, Update, Iterator,
+// Info, ...), and Close must be called to release it.
+func NewBPFMapFromID(id uint32) (*BPFMap, error) {
+	fd := C.bpf_map_get_fd_by_id(C.__u32(id))
+	if fd < 0 {
+		return nil, fmt.Errorf("failed to open map %d: %w", id, syscall.Errno(-fd))
+	}
+	return newBPFMapFromFD(fd)
+}
+
+func newBPFMapFromFD(fd C.int) (*BPFMap, error) {
+	info, err := mapInfoByFD(int(fd))
+	if err != nil {
+		C.close(fd)
+		return nil, fmt.Errorf("failed to get info of map: %w", err)
+	}
+	return &BPFMap{
+		name: info.Name,
+		fd:   fd,
+		info: info,
+	}, nil
+}
+
+// Close releases a map opened outside of a module. Maps of a module are
+// released by Module.Close.
+func (b *BPFMap) Close() error {
+	if b.bpfMap != nil || b.fd < 0 {
+		return nil
+	}
+	if err := b.Unmap(); err != nil {
+		return err
+	}
+	if ret, errno := C.close(b.fd); ret < 0 {
+		return fmt.Errorf("failed to close map %s: %w", b.name, errno)
+	}
+	b.fd = -1
+	return nil
+}
+
+// BPFLinkType is an enum as defined in https://elixir.bootlin.com/linux/latest/source/include/uapi/linux/bpf.h
+type BPFLinkType uint32
+
+const (
+	BPFLinkTypeUnspec BPFLinkType = iota
+	BPFLinkTypeRawTracepoint
+	BPFLinkTypeTracing
+	BPFLinkTypeCgroup
+	BPFLinkTypeIter
+	BPFLinkTypeNetns
+	BPFLinkTypeXDP
+	BPFLinkTypePerfEvent
+	BPFLinkTypeKprobeMulti
+	BPFLinkTypeStructOps
+)
+
+func (t BPFLinkType) String() string {
+	x := map[BPFLinkType]string{
+		BPFLinkTypeUnspec:        "BPF_LINK_TYPE_UNSPEC",
+		BPFLinkTypeRawTracepoint: "BPF_LINK_TYPE_RAW_TRACEPOINT",
+		BPFLinkTypeTracing:       "BPF_LINK_TYPE_TRACING",
+		BPFLinkTypeCgroup:        "BPF_LINK_TYPE_CGROUP",
+		BPFLinkTypeIter:          "BPF_LINK_TYPE_ITER",
+		BPFLinkTypeNetns:         "BPF_LINK_TYPE_NETNS",
+		BPFLinkTypeXDP:           "BPF_LINK_TYPE_XDP",
+		BPFLinkTypePerfEvent:     "BPF_LINK_TYPE_PERF_EVENT",
+		BPFLinkTypeKprobeMulti:   "BPF_LINK_TYPE_KPROBE_MULTI",
+		BPFLinkTypeStructOps:     "BPF_LINK_TYPE_STRUCT_OPS",
+	}
+	str, ok := x[t]
+	if !ok {
+		str = fmt.Sprintf("BPF_LINK_TYPE_UNKNOWN(%d)", uint32(t))
+	}
+	return str
+}
+
+// BPFLinkInfo is the kernel view of a link
+type BPFLinkInfo struct {
+	Type   BPFLinkType
+	ID     uint32
+	ProgID uint32
+}
+
+// NewBPFLinkFromID opens the link id. Close must be called to release it,
+// which does not detach it.
+func NewBPFLinkFromID(id uint32) (*BPFLink, error) {
+	fd := C.bpf_link_get_fd_by_id(C.__u32(id))
+	if fd < 0 {
+		return nil, fmt.Errorf("failed to open link %d: %w", id, syscall.Errno(-fd))
+	}
+	return newBPFLinkFromFD(fd)
+}
+
+func newBPFLinkFromFD(fd C.int) (*BPFLink, error) {
+	info, err := linkInfoByFD(int(fd))
+	if err != nil {
+		C.close(fd)
+		return nil, fmt.Errorf("failed to get info of link: %w", err)
+	}
+	return &BPFLink{
+		fd:        fd,
+		eventName: fmt.Sprintf("%s:%d", info.Type, info.ID),
+	}, nil
+}
+
+// Info returns the kernel view of the link
+func (l *BPFLink) Info() (*BPFLinkInfo, error) {
+	info, err := linkInfoByFD(l.GetFd())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of link %s: %w", l.eventName, err)
+	}
+	return info, nil
+}
+
+// Close releases a link opened outside of a module, without detaching it.
+// Links of a module are released by Module.Close.
+func (l *BPFLink) Close() error {
+	if l.link != nil || l.prog != nil || l.fd < 0 {
+		return nil
+	}
+	if ret, errno := C.close(l.fd); ret < 0 {
+		return fmt.Errorf("failed to close link %s: %w", l.eventName, errno)
+	}
+	l.fd = -1
+	return nil
+}
+
+func linkInfoByFD(fd int) (*BPFLinkInfo, error) {
+	var info C.struct_bpf_link_info
+
+	errC := C.get_link_info(C.int(fd), &info)
+	if errC < 0 {
+		return nil, syscall.Errno(-errC)
+	}
+
+	return &BPFLinkInfo{
+		Type:   BPFLinkType(info._type),
+		ID:     uint32(info.id),
+		ProgID: uint32(info.prog_id),
+	}, nil
+}
+
+// BPFBTFInfo is the kernel view of a BTF object
+type BPFBTFInfo struct {
+	ID        uint32
+	Name      string // vmlinux, a kernel module, or empty for BTF loaded by programs
+	Size      uint32
+	KernelBTF bool
+}
+
+// GetBTFInfoByID returns the kernel view of the BTF object id
+func GetBTFInfoByID(id uint32) (*BPFBTFInfo, error) {
+	info, _, err := btfByID(id, false)
+	return info, err
+}
+
+// LoadBTFByID parses the BTF object id. The BTF of kernel modules is split
+// BTF, parsed on top of the vmlinux BTF.
+func LoadBTFByID(id uint32) (*btf.Spec, error) {
+	info, data, err := btfByID(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var base *btf.Spec
+	if info.KernelBTF && info.Name != "vmlinux" {
+		if base, err = btf.LoadKernelSpec(); err != nil {
+			return nil, fmt.Errorf("failed to load vmlinux BTF, base of BTF %d: %w", id, err)
+		}
+	}
+
+	spec, err := btf.ParseSplit(data, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BTF %d: %w", id, err)
+	}
+	return spec, nil
+}
+
+func btfByID(id uint32, withData bool) (*BPFBTFInfo, []byte, error) {
+	fd := C.bpf_btf_get_fd_by_id(C.__u32(id))
+	if fd < 0 {
+		return nil, nil, fmt.Errorf("failed to open BTF %d: %w", id, syscall.Errno(-fd))
+	}
+	defer C.close(fd)
+
+	const nameLen = 64
+	name := (*C.char)(C.calloc(nameLen, 1))
+	defer C.free(unsafe.Pointer(name))
+
+	var info C.struct_bpf_btf_info
+	errC := C.get_btf_info(fd, &info, nil, 0, name, nameLen)
+	if errC < 0 {
+		return nil, nil, fmt.Errorf("failed to get info of BTF %d: %w", id, syscall.Errno(-errC))
+	}
+
+	btfInfo := &BPFBTFInfo{
+		ID:        uint32(info.id),
+		Name:      C.GoString(name),
+		Size:      uint32(info.btf_size),
+		KernelBTF: info.kernel_btf != 0,
+	}
+	if !withData {
+		return btfInfo, nil, nil
+	}
+
+	// ask again, now that the size is known
+	size := info.btf_size
+	data := C.malloc(C.size_t(size))
+	defer C.free(data)
+
+	errC = C.get_btf_info(fd, &info, data, size, name, nameLen)
+	if errC < 0 {
+		return nil, nil, fmt.Errorf("failed to get data of BTF %d: %w", id, syscall.Errno(-errC))
+	}
+	if info.btf_size != size {
+		return nil, nil, fmt.Errorf("BTF %d changed size while being read", id)
+	}
+
+	return btfInfo, C.GoBytes(data, C.int(size)), nil
+}
@@ -0,0 +1,228 @@
+package libbpfgo
+
+/*
+#include <stdlib.h>
+#include <string.h>
+
+#include <bpf/bpf.h>
+
+static int get_prog_info(int fd, struct bpf_prog_info *info, __u32 *map_ids, __u32 nr_map_ids)
+{
+    __u32 len = sizeof(*info);
+
+    memset(info, 0, len);
+    info->nr_map_ids = nr_map_ids;
+    info->map_ids = (__u64) (unsigned long) map_ids;
+
+    return bpf_obj_get_info_by_fd(fd, info, &len);
+}
+
+// cgo can't access bitfields
+static int get_prog_info_gpl_compatible(struct bpf_prog_info *info)
+{
+    return info->gpl_compatible;
+}
+
+static int get_map_info(int fd, struct bpf_map_info *info)
+{
+    __u32 len = sizeof(*info);
+
+    memset(info, 0, len);
+
+    return bpf_obj_get_info_by_fd(fd, info, &len);
+}
+*/
+import "C"
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// BPFProgInfo is the kernel view of a loaded program
+type BPFProgInfo struct {
+	Type            BPFProgType
+	ID              uint32
+	Tag             string // hex encoded, as shown by bpftool
+	Name            string // truncated to 15 characters by the kernel
+	LoadTime        time.Time
+	CreatedByUID    uint32
+	XlatedProgLen   uint32 // in bytes
+	JitedProgLen    uint32 // in bytes
+	MapIDs          []uint32
+	BTFID           uint32
+	VerifiedInsns   uint32
+	GPLCompatible   bool
+	RunCnt          uint64 // only counted while stats are enabled (BPF_ENABLE_STATS)
+	RunTime         time.Duration
+	RecursionMisses uint64
+	Memlock         uint64 // in bytes
+}
+
+// BPFMapInfo is the kernel view of a map
+type BPFMapInfo struct {
+	Type                  MapType
+	ID                    uint32
+	Name                  string // truncated to 15 characters by the kernel
+	KeySize               uint32
+	ValueSize             uint32
+	MaxEntries            uint32
+	MapFlags              uint32
+	MapExtra              uint64
+	Ifindex               uint32
+	BTFID                 uint32
+	BTFKeyTypeID          uint32
+	BTFValueTypeID        uint32
+	BTFVmlinuxValueTypeID uint32
+	Frozen                bool
+	Memlock               uint64 // in bytes
+}
+
+// Info returns the kernel view of the program, which must be loaded
+func (p *BPFProg) Info() (*BPFProgInfo, error) {
+	info, err := progInfoByFD(p.GetFd())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of program %s: %w", p.name, err)
+	}
+	return info, nil
+}
+
+// Info returns the kernel view of the map, which must be loaded
+func (b *BPFMap) Info() (*BPFMapInfo, error) {
+	info, err := mapInfoByFD(b.GetFd())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of map %s: %w", b.name, err)
+	}
+	return info, nil
+}
+
+func progInfoByFD(fd int) (*BPFProgInfo, error) {
+	var info C.struct_bpf_prog_info
+
+	errC := C.get_prog_info(C.int(fd), &info, nil, 0)
+	if errC < 0 {
+		return nil, syscall.Errno(-errC)
+	}
+
+	// ask again, now that the number of maps is known
+	var mapIDs []uint32
+	if n := info.nr_map_ids; n > 0 {
+		ids := (*C.__u32)(C.calloc(C.size_t(n), C.sizeof___u32))
+		defer C.free(unsafe.Pointer(ids))
+
+		errC = C.get_prog_info(C.int(fd), &info, ids, n)
+		if errC < 0 {
+			return nil, syscall.Errno(-errC)
+		}
+		if info.nr_map_ids < n {
+			n = info.nr_map_ids
+		}
+		for _, id := range unsafe.Slice(ids, int(n)) {
+			mapIDs = append(mapIDs, uint32(id))
+		}
+	}
+
+	fields, err := fdinfo(fd)
+	if err != nil {
+		return nil, err
+	}
+	memlock, _ := strconv.ParseUint(fields["memlock"], 10, 64)
+
+	return &BPFProgInfo{
+		Type:            BPFProgType(info._type),
+		ID:              uint32(info.id),
+		Tag:             hex.EncodeToString(C.GoBytes(unsafe.Pointer(&info.tag[0]), C.BPF_TAG_SIZE)),
+		Name:            C.GoString(&info.name[0]),
+		LoadTime:        bootTime().Add(time.Duration(info.load_time)),
+		CreatedByUID:    uint32(info.created_by_uid),
+		XlatedProgLen:   uint32(info.xlated_prog_len),
+		JitedProgLen:    uint32(info.jited_prog_len),
+		MapIDs:          mapIDs,
+		BTFID:           uint32(info.btf_id),
+		VerifiedInsns:   uint32(info.verified_insns),
+		GPLCompatible:   C.get_prog_info_gpl_compatible(&info) != 0,
+		RunCnt:          uint64(info.run_cnt),
+		RunTime:         time.Duration(info.run_time_ns),
+		RecursionMisses: uint64(info.recursion_misses),
+		Memlock:         memlock,
+	}, nil
+}
+
+func mapInfoByFD(fd int) (*BPFMapInfo, error) {
+	var info C.struct_bpf_map_info
+
+	errC := C.get_map_info(C.int(fd), &info)
+	if errC < 0 {
+		return nil, syscall.Errno(-errC)
+	}
+
+	fields, err := fdinfo(fd)
+	if err != nil {
+		return nil, err
+	}
+	memlock, _ := strconv.ParseUint(fields["memlock"], 10, 64)
+
+	return &BPFMapInfo{
+		Type:                  MapType(info._type),
+		ID:                    uint32(info.id),
+		Name:                  C.GoString(&info.name[0]),
+		KeySize:               uint32(info.key_size),
+		ValueSize:             uint32(info.value_size),
+		MaxEntries:            uint32(info.max_entries),
+		MapFlags:              uint32(info.map_flags),
+		MapExtra:              uint64(info.map_extra),
+		Ifindex:               uint32(info.ifindex),
+		BTFID:                 uint32(info.btf_id),
+		BTFKeyTypeID:          uint32(info.btf_key_type_id),
+		BTFValueTypeID:        uint32(info.btf_value_type_id),
+		BTFVmlinuxValueTypeID: uint32(info.btf_vmlinux_value_type_id),
+		Frozen:                fields["frozen"] == "1",
+		Memlock:               memlock,
+	}, nil
+}
+
+// bootTime returns when the system booted, as load times are relative to it
+func bootTime() time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return time.Time{}
+	}
+	return time.Now().Add(-time.Duration(ts.Nano()))
+}
+
+// fdinfo returns the fields of /proc/self/fdinfo/<fd>, where the kernel
+// reports what bpf_obj_get_info_by_fd does not (memlock, frozen, ...)
+func fdinfo(fd int) (map[string]string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/self/fdinfo/%d", fd))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseFdinfo(f)
+}
+
+func parseFdinfo(r io.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return fields, scanner.Err()
+}
@@ -0,0 +1,43 @@
+package libbpfgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFdinfo(t *testing.T) {
+	content := `pos:	0
+flags:	02000002
+mnt_id:	15
+ino:	1057
+map_type:	1
+key_size:	4
+value_size:	8
+max_entries:	1024
+map_flags:	0x0
+map_extra:	0x0
+memlock:	90112
+map_id:	42
+frozen:	1
+`
+
+	fields, err := parseFdinfo(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"memlock":   "90112",
+		"map_id":    "42",
+		"frozen":    "1",
+		"map_flags": "0x0",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected %s to be %q, got %q", key, value, fields[key])
+		}
+	}
+	if len(fields) != 13 {
+		t.Errorf("expected 13 fields, got %d", len(fields))
+	}
+}
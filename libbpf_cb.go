@@ -28,3 +28,8 @@ func ringbufferCallback(ctx unsafe.Pointer, data unsafe.Pointer, size C.int) C.i
 	ch <- C.GoBytes(data, size)
 	return C.int(0)
 }
+
+//export loggerCallback
+func loggerCallback(level C.int, msg *C.char) {
+	libbpfLogger.log(LogLevel(level), currentLogObject(), C.GoString(msg))
+}
@@ -8,6 +8,7 @@ package libbpfgo
 #include <sys/resource.h>
 
 #include <bpf/bpf.h>
+#include <bpf/btf.h>
 #include <bpf/libbpf.h>
 
 #ifndef MAX_ERRNO
@@ -30,23 +31,36 @@ static inline long PTR_ERR(const void *ptr)
 }
 #endif
 
+extern void loggerCallback(int level, char *msg);
+
 int libbpf_print_fn(enum libbpf_print_level level, const char *format,
                     va_list args)
 {
-    if (level != LIBBPF_WARN)
-        return 0;
+    int ret;
+    size_t len;
+    char *out;
+    va_list check;
 
-	// BUG: https://github.com/aquasecurity/tracee/issues/1676
+    va_copy(check, args);
+    ret = vsnprintf(NULL, 0, format, check);
+    va_end(check);
+    if (ret < 0)
+        return ret;
 
-	va_list check; va_copy(check, args);
-	char *str = va_arg(check, char *);
-	if (strstr(str, "Exclusivity flag on") != NULL) {
-		va_end(check);
-		return 0;
-	}
-	va_end(check);
+    len = ret + 1;
+    out = malloc(len);
+    if (!out)
+        return -ENOMEM;
+
+    va_copy(check, args);
+    ret = vsnprintf(out, len, format, check);
+    va_end(check);
+
+    if (ret > 0)
+        loggerCallback(level, out);
 
-    return vfprintf(stderr, format, args);
+    free(out);
+    return ret;
 }
 
 void set_print_fn() {
@@ -92,11 +106,13 @@ import "C"
 import (
 	"fmt"
 	"net"
+	"os"
 	"path/filepath"
 	"sync"
 	"syscall"
 	"unsafe"
 
+	"github.com/aquasecurity/libbpfgo/btf"
 	"github.com/aquasecurity/libbpfgo/helpers"
 )
 
@@ -126,6 +142,15 @@ type Module struct {
 	links    []*BPFLink
 	perfBufs []*PerfBuffer
 	ringBufs []*RingBuffer
+	logSize  uint32
+	logBuf   unsafe.Pointer                           // shared by the programs logging only failures
+	logBufs  map[*C.struct_bpf_program]unsafe.Pointer // log buffers of their own, see SetLogSize
+	failed   *C.struct_bpf_program                    // program BPFLoadObject failed on, if known
+	loaded   bool
+
+	innerMaps  map[string]*BPFMapInfo     // templates set with SetInnerMap, by outer map
+	mmaped     []*BPFMap                  // maps to unmap on Close
+	globalData map[*C.struct_bpf_map]bool // internal maps mmaped on first access, false if it failed
 }
 
 type BPFMap struct {
@@ -133,6 +158,11 @@ type BPFMap struct {
 	bpfMap *C.struct_bpf_map
 	fd     C.int
 	module *Module
+	info   *BPFMapInfo // for maps not backed by a libbpf object
+
+	innerMap  *BPFMapInfo     // inner map template of standalone maps of maps
+	formatter *EntryFormatter // loaded by the first FormatEntry
+	mmaped    []byte          // see Mmap
 }
 
 type MapType uint32
@@ -222,6 +252,8 @@ type BPFProg struct {
 	prog       *C.struct_bpf_program
 	module     *Module
 	pinnedPath string
+	fd         C.int        // for programs not backed by a libbpf object
+	info       *BPFProgInfo // likewise
 }
 
 type LinkType int
@@ -244,23 +276,46 @@ type BPFLink struct {
 	prog      *BPFProg
 	linkType  LinkType
 	eventName string
+	fd        C.int // for links not backed by a libbpf object
 }
 
 func (l *BPFLink) Destroy() error {
+	if l.link == nil {
+		// opened by ID or from a pin, or already destroyed
+		if l.fd < 0 {
+			return nil
+		}
+		if err := syscall.Close(int(l.fd)); err != nil {
+			return err
+		}
+		l.fd = -1
+		return nil
+	}
+
 	ret := C.bpf_link__destroy(l.link)
 	if ret < 0 {
 		return syscall.Errno(-ret)
 	}
 	l.link = nil
+	l.fd = -1
 
 	return nil
 }
 
 func (l *BPFLink) GetFd() int {
+	if l.link == nil {
+		return int(l.fd)
+	}
 	return int(C.bpf_link__fd(l.link))
 }
 
 func (l *BPFLink) Pin(pinPath string) error {
+	if l.link == nil {
+		if err := objPin(l.fd, pinPath); err != nil {
+			return fmt.Errorf("failed to pin link %s to path %s: %w", l.eventName, pinPath, err)
+		}
+		return nil
+	}
 	path := C.CString(pinPath)
 	errC := C.bpf_link__pin(l.link, path)
 	C.free(unsafe.Pointer(path))
@@ -271,6 +326,12 @@ func (l *BPFLink) Pin(pinPath string) error {
 }
 
 func (l *BPFLink) Unpin(pinPath string) error {
+	if l.link == nil {
+		if err := os.Remove(pinPath); err != nil {
+			return fmt.Errorf("failed to unpin link %s from path %s: %w", l.eventName, pinPath, err)
+		}
+		return nil
+	}
 	path := C.CString(pinPath)
 	errC := C.bpf_link__unpin(l.link)
 	C.free(unsafe.Pointer(path))
@@ -330,6 +391,9 @@ type NewModuleArgs struct {
 	BPFObjName      string
 	BPFObjPath      string
 	BPFObjBuff      []byte
+	KernelLogLevel  uint32 // verifier log level for all programs (see BPFProg.SetLogLevel)
+	KernelLogSize   uint32 // size of the verifier log buffers of the programs (see BPFProg.SetLogSize)
+	SkipMemlockBump bool   // do not raise RLIMIT_MEMLOCK, e.g. when the object is only inspected
 }
 
 func NewModuleFromFile(bpfObjPath string) (*Module, error) {
@@ -378,8 +442,10 @@ func SetStrictMode(mode LibbpfStrictMode) {
 
 func NewModuleFromFileArgs(args NewModuleArgs) (*Module, error) {
 	C.set_print_fn()
-	if err := bumpMemlockRlimit(); err != nil {
-		return nil, err
+	if !args.SkipMemlockBump {
+		if err := bumpMemlockRlimit(); err != nil {
+			return nil, err
+		}
 	}
 	opts := C.struct_bpf_object_open_opts{}
 	opts.sz = C.sizeof_struct_bpf_object_open_opts
@@ -401,14 +467,15 @@ func NewModuleFromFileArgs(args NewModuleArgs) (*Module, error) {
 		defer C.free(unsafe.Pointer(kConfigFile))
 	}
 
-	obj := C.bpf_object__open_file(bpfFile, &opts)
+	var obj *C.struct_bpf_object
+	withLogObject(objNameFromPath(args.BPFObjPath), func() {
+		obj = C.bpf_object__open_file(bpfFile, &opts)
+	})
 	if C.IS_ERR_OR_NULL(unsafe.Pointer(obj)) {
 		return nil, errptrError(unsafe.Pointer(obj), "failed to open BPF object %s", args.BPFObjPath)
 	}
 
-	return &Module{
-		obj: obj,
-	}, nil
+	return newModule(obj, args)
 }
 
 func NewModuleFromBuffer(bpfObjBuff []byte, bpfObjName string) (*Module, error) {
@@ -421,8 +488,10 @@ func NewModuleFromBuffer(bpfObjBuff []byte, bpfObjName string) (*Module, error)
 
 func NewModuleFromBufferArgs(args NewModuleArgs) (*Module, error) {
 	C.set_print_fn()
-	if err := bumpMemlockRlimit(); err != nil {
-		return nil, err
+	if !args.SkipMemlockBump {
+		if err := bumpMemlockRlimit(); err != nil {
+			return nil, err
+		}
 	}
 	if args.BTFObjPath == "" {
 		args.BTFObjPath = "/sys/kernel/btf/vmlinux"
@@ -443,7 +512,10 @@ func NewModuleFromBufferArgs(args NewModuleArgs) (*Module, error) {
 		defer C.free(unsafe.Pointer(kConfigFile))
 	}
 
-	obj := C.bpf_object__open_mem(bpfBuff, bpfBuffSize, &opts)
+	var obj *C.struct_bpf_object
+	withLogObject(args.BPFObjName, func() {
+		obj = C.bpf_object__open_mem(bpfBuff, bpfBuffSize, &opts)
+	})
 	if C.IS_ERR_OR_NULL(unsafe.Pointer(obj)) {
 		return nil, errptrError(unsafe.Pointer(obj), "failed to open BPF object %s: %v", args.BPFObjName, args.BPFObjBuff[:20])
 	}
@@ -452,9 +524,47 @@ func NewModuleFromBufferArgs(args NewModuleArgs) (*Module, error) {
 	C.free(unsafe.Pointer(bpfName))
 	C.free(unsafe.Pointer(btfFile))
 
-	return &Module{
-		obj: obj,
-	}, nil
+	return newModule(obj, args)
+}
+
+func newModule(obj *C.struct_bpf_object, args NewModuleArgs) (*Module, error) {
+	m := &Module{
+		obj:     obj,
+		logSize: args.KernelLogSize,
+	}
+
+	if args.KernelLogLevel != 0 {
+		it := m.Iterator()
+		for prog := it.NextProgram(); prog != nil; prog = it.NextProgram() {
+			if err := prog.SetLogLevel(args.KernelLogLevel); err != nil {
+				C.bpf_object__close(obj)
+				return nil, err
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Name returns the name libbpf gave to the BPF object
+func (m *Module) Name() string {
+	return C.GoString(C.bpf_object__name(m.obj))
+}
+
+// BTF parses the BTF of the BPF object, to inspect its types with the btf
+// package
+func (m *Module) BTF() (*btf.Spec, error) {
+	objBTF := C.bpf_object__btf(m.obj)
+	if objBTF == nil {
+		return nil, fmt.Errorf("BPF object %s has no BTF: %w", m.Name(), syscall.ENOENT)
+	}
+
+	var size C.__u32
+	data := C.btf__raw_data(objBTF, &size)
+	if data == nil {
+		return nil, fmt.Errorf("failed to get BTF of BPF object %s: %w", m.Name(), syscall.ENOMEM)
+	}
+	return btf.Parse(C.GoBytes(data, C.int(size)))
 }
 
 func (m *Module) Close() {
@@ -469,18 +579,125 @@ func (m *Module) Close() {
 			C.bpf_link__destroy(link.link)
 		}
 	}
+	for _, bpfMap := range m.mmaped {
+		syscall.Munmap(bpfMap.mmaped[:cap(bpfMap.mmaped)])
+		bpfMap.mmaped = nil
+	}
 	C.bpf_object__close(m.obj)
+	for _, buf := range m.logBufs {
+		C.free(buf)
+	}
+	C.free(m.logBuf)
 }
 
+// BPFLoadObject loads all the maps and programs of the module into the
+// kernel. If loading fails, the returned error is a *LoadError carrying
+// the verifier log of the rejected program.
 func (m *Module) BPFLoadObject() error {
-	ret := C.bpf_object__load(m.obj)
+	shared, err := m.setLogBufs()
+	if err != nil {
+		return err
+	}
+
+	var ret C.int
+	withLogObject(m.Name(), func() {
+		ret = C.bpf_object__load(m.obj)
+	})
 	if ret != 0 {
-		return fmt.Errorf("failed to load BPF object")
+		return m.loadError(syscall.Errno(-ret), shared)
 	}
+	m.loaded = true
 
 	return nil
 }
 
+// setLogBufs gives the autoload programs a verifier log buffer of
+// KernelLogSize bytes. Programs with a log level get one of their own, as
+// the kernel logs them even when they load fine. The others, only logged
+// when rejected, share one buffer for the object: program i of shared
+// writes at offset i, so that the first non-zero byte tells which one was
+// rejected.
+func (m *Module) setLogBufs() (shared []*BPFProg, err error) {
+	size := m.logSize
+	if size == 0 {
+		size = defaultKernelLogSize
+	}
+
+	it := m.Iterator()
+	for prog := it.NextProgram(); prog != nil; prog = it.NextProgram() {
+		if !prog.autoload() || m.logBufs[prog.prog] != nil {
+			continue
+		}
+		if prog.GetLogLevel() != 0 {
+			if err = prog.SetLogSize(size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		shared = append(shared, prog)
+	}
+	if len(shared) == 0 {
+		return nil, nil
+	}
+
+	C.free(m.logBuf)
+	m.logBuf = C.calloc(C.size_t(size)+C.size_t(len(shared)), 1)
+	if m.logBuf == nil {
+		return nil, fmt.Errorf("failed to allocate log buffer for BPF object %s", m.Name())
+	}
+	for i, prog := range shared {
+		errC := C.bpf_program__set_log_buf(prog.prog, (*C.char)(unsafe.Add(m.logBuf, i)), C.size_t(size))
+		if errC != 0 {
+			return nil, fmt.Errorf("failed to set log buffer for program %s: %w", prog.name, syscall.Errno(-errC))
+		}
+	}
+	return shared, nil
+}
+
+// loadError tells which program made bpf_object__load fail. A program of
+// shared that got a log was rejected, see setLogBufs. Otherwise, programs
+// are loaded in order and libbpf stops at the first failure, so it is the
+// last one that got a log in a buffer of its own.
+func (m *Module) loadError(errno syscall.Errno, shared []*BPFProg) error {
+	loadErr := &LoadError{
+		Errno: errno,
+	}
+
+	if len(shared) > 0 {
+		if i := firstLogged(unsafe.Slice((*byte)(m.logBuf), len(shared))); i >= 0 {
+			m.failed = shared[i].prog
+			loadErr.ProgName = shared[i].name
+			loadErr.Log = shared[i].GetLog()
+			return loadErr
+		}
+	}
+
+	it := m.Iterator()
+	for prog := it.NextProgram(); prog != nil; prog = it.NextProgram() {
+		if !prog.autoload() || m.logBufs[prog.prog] == nil {
+			continue
+		}
+		if log := prog.GetLog(); log != "" {
+			m.failed = prog.prog
+			loadErr.ProgName = prog.name
+			loadErr.Log = log
+		}
+	}
+
+	return loadErr
+}
+
+// firstLogged returns the index of the first program that wrote to a shared
+// log buffer, from its first bytes, or -1
+func firstLogged(head []byte) int {
+	for i, b := range head {
+		if b != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
 // BPFMapCreateOpts mirrors the C structure bpf_map_create_opts
 type BPFMapCreateOpts struct {
 	Size                  uint64
@@ -516,9 +733,11 @@ func bpfMapCreateOptsToC(createOpts *BPFMapCreateOpts) *C.struct_bpf_map_create_
 
 // CreateMap creates a BPF map from userspace. This can be used for populating
 // BPF array of maps or hash of maps. However, this function uses a low-level
-// libbpf API; maps created in this way do not conform to libbpf map formats,
-// and therefore do not have access to libbpf high level bpf_map__* APIS
-// which causes different behavior from maps created in the kernel side code
+// libbpf API; maps created in this way are not part of any module and their
+// definition is read from the kernel once created. They support all the
+// lookup, update, batch and iterator methods, but not the setters meant to
+// be called before loading (SetType, Resize, ...). Close must be called to
+// release them.
 //
 // See usage of `bpf_map_create()` in kernel selftests for more info
 func CreateMap(mapType MapType, mapName string, keySize, valueSize, maxEntries int, opts *BPFMapCreateOpts) (*BPFMap, error) {
@@ -529,12 +748,20 @@ func CreateMap(mapType MapType, mapName string, keySize, valueSize, maxEntries i
 		return nil, fmt.Errorf("could not create map: %w", syscall.Errno(-fdOrError))
 	}
 
-	return &BPFMap{
-		name:   mapName,
-		fd:     fdOrError,
-		module: nil,
-		bpfMap: nil,
-	}, nil
+	bpfMap, err := newBPFMapFromFD(fdOrError)
+	if err != nil {
+		return nil, fmt.Errorf("could not create map: %w", err)
+	}
+	// the kernel truncates names
+	bpfMap.name = mapName
+
+	if opts != nil && opts.InnerMapFD != 0 {
+		if bpfMap.innerMap, err = mapInfoByFD(int(opts.InnerMapFD)); err != nil {
+			bpfMap.Close()
+			return nil, fmt.Errorf("could not get inner map of map %s: %w", mapName, err)
+		}
+	}
+	return bpfMap, nil
 }
 
 func (m *Module) GetMap(mapName string) (*BPFMap, error) {
@@ -553,7 +780,32 @@ func (m *Module) GetMap(mapName string) (*BPFMap, error) {
 	}, nil
 }
 
+// ShareMapFrom makes the map mapName of the module use the map of the
+// same name of other, which must be loaded. It should be called prior to
+// loading the module with BPFLoadObject.
+func (m *Module) ShareMapFrom(other *Module, mapName string) error {
+	if !other.loaded {
+		return fmt.Errorf("failed to share map %s: module %s is not loaded", mapName, other.Name())
+	}
+	if m.loaded {
+		return fmt.Errorf("failed to share map %s: module %s is already loaded", mapName, m.Name())
+	}
+
+	otherMap, err := other.GetMap(mapName)
+	if err != nil {
+		return err
+	}
+	bpfMap, err := m.GetMap(mapName)
+	if err != nil {
+		return err
+	}
+	return bpfMap.ReuseFD(otherMap.GetFd())
+}
+
 func (b *BPFMap) Name() string {
+	if b.bpfMap == nil {
+		return b.name
+	}
 	cs := C.bpf_map__name(b.bpfMap)
 	if cs == nil {
 		return ""
@@ -563,6 +815,9 @@ func (b *BPFMap) Name() string {
 }
 
 func (b *BPFMap) Type() MapType {
+	if b.bpfMap == nil {
+		return b.info.Type
+	}
 	return MapType(C.bpf_map__type(b.bpfMap))
 }
 
@@ -570,6 +825,9 @@ func (b *BPFMap) Type() MapType {
 // with a file descriptor already. If the map is already associated
 // with a file descriptor the libbpf API will return error code EBUSY
 func (b *BPFMap) SetType(mapType MapType) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("could not set bpf map type: %w", syscall.EBUSY)
+	}
 	errC := C.bpf_map__set_type(b.bpfMap, C.enum_bpf_map_type(int(mapType)))
 	if errC != 0 {
 		return fmt.Errorf("could not set bpf map type: %w", syscall.Errno(-errC))
@@ -578,6 +836,12 @@ func (b *BPFMap) SetType(mapType MapType) error {
 }
 
 func (b *BPFMap) Pin(pinPath string) error {
+	if b.bpfMap == nil {
+		if err := objPin(b.fd, pinPath); err != nil {
+			return fmt.Errorf("failed to pin map %s to path %s: %w", b.name, pinPath, err)
+		}
+		return nil
+	}
 	path := C.CString(pinPath)
 	ret, errC := C.bpf_map__pin(b.bpfMap, path)
 	C.free(unsafe.Pointer(path))
@@ -588,6 +852,12 @@ func (b *BPFMap) Pin(pinPath string) error {
 }
 
 func (b *BPFMap) Unpin(pinPath string) error {
+	if b.bpfMap == nil {
+		if err := os.Remove(pinPath); err != nil {
+			return fmt.Errorf("failed to unpin map %s from path %s: %w", b.name, pinPath, err)
+		}
+		return nil
+	}
 	path := C.CString(pinPath)
 	ret, errC := C.bpf_map__unpin(b.bpfMap, path)
 	C.free(unsafe.Pointer(path))
@@ -598,6 +868,9 @@ func (b *BPFMap) Unpin(pinPath string) error {
 }
 
 func (b *BPFMap) SetPinPath(pinPath string) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("failed to set pin for map %s to path %s: %w", b.name, pinPath, syscall.EBUSY)
+	}
 	path := C.CString(pinPath)
 	ret, errC := C.bpf_map__set_pin_path(b.bpfMap, path)
 	C.free(unsafe.Pointer(path))
@@ -613,6 +886,9 @@ func (b *BPFMap) SetPinPath(pinPath string) error {
 // Note: for ring buffer and perf buffer, maxEntries is the
 // capacity in bytes.
 func (b *BPFMap) Resize(maxEntries uint32) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("failed to resize map %s to %v: %w", b.name, maxEntries, syscall.EBUSY)
+	}
 	ret, errC := C.bpf_map__set_max_entries(b.bpfMap, C.uint(maxEntries))
 	if ret != 0 {
 		return fmt.Errorf("failed to resize map %s to %v: %w", b.name, maxEntries, errC)
@@ -620,10 +896,51 @@ func (b *BPFMap) Resize(maxEntries uint32) error {
 	return nil
 }
 
+// SetMapExtra sets the map_extra of the map: for bloom filters, the number
+// of hash functions in its lower 4 bits (5 if 0). It should be called prior
+// to loading the module with BPFLoadObject.
+func (b *BPFMap) SetMapExtra(mapExtra uint64) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("failed to set map extra of map %s to %d: %w", b.name, mapExtra, syscall.EBUSY)
+	}
+	errC := C.bpf_map__set_map_extra(b.bpfMap, C.__u64(mapExtra))
+	if errC != 0 {
+		return fmt.Errorf("failed to set map extra of map %s to %d: %w", b.name, mapExtra, syscall.Errno(-errC))
+	}
+	return nil
+}
+
+func (b *BPFMap) MapExtra() uint64 {
+	if b.bpfMap == nil {
+		return b.info.MapExtra
+	}
+	return uint64(C.bpf_map__map_extra(b.bpfMap))
+}
+
+// ReuseFD makes the map use the already created map fd instead of creating
+// its own when the module is loaded, so that several modules can share a
+// map. It should be called prior to loading the module with BPFLoadObject,
+// with a map of the same definition. fd is duplicated, it can be closed
+// afterwards.
+func (b *BPFMap) ReuseFD(fd int) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("failed to reuse fd %d for map %s: %w", fd, b.name, syscall.EINVAL)
+	}
+	errC := C.bpf_map__reuse_fd(b.bpfMap, C.int(fd))
+	if errC != 0 {
+		return fmt.Errorf("failed to reuse fd %d for map %s: %w", fd, b.name, syscall.Errno(-errC))
+	}
+	b.fd = C.bpf_map__fd(b.bpfMap)
+	return nil
+}
+
 // GetMaxEntries returns the map's capacity.
 // Note: for ring buffer and perf buffer, maxEntries is the
 // capacity in bytes.
 func (b *BPFMap) GetMaxEntries() uint32 {
+	if b.bpfMap == nil {
+		return b.info.MaxEntries
+	}
 	maxEntries := C.bpf_map__max_entries(b.bpfMap)
 	return uint32(maxEntries)
 }
@@ -641,24 +958,39 @@ func (b *BPFMap) GetModule() *Module {
 }
 
 func (b *BPFMap) GetPinPath() string {
+	if b.bpfMap == nil {
+		return ""
+	}
 	pinPathGo := C.GoString(C.bpf_map__get_pin_path(b.bpfMap))
 	return pinPathGo
 }
 
 func (b *BPFMap) IsPinned() bool {
+	if b.bpfMap == nil {
+		return false
+	}
 	isPinned := C.bpf_map__is_pinned(b.bpfMap)
 	return isPinned == C.bool(true)
 }
 
 func (b *BPFMap) KeySize() int {
+	if b.bpfMap == nil {
+		return int(b.info.KeySize)
+	}
 	return int(C.bpf_map__key_size(b.bpfMap))
 }
 
 func (b *BPFMap) ValueSize() int {
+	if b.bpfMap == nil {
+		return int(b.info.ValueSize)
+	}
 	return int(C.bpf_map__value_size(b.bpfMap))
 }
 
 func (b *BPFMap) SetValueSize(size uint32) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("could not set map value size: %w", syscall.EBUSY)
+	}
 	errC := C.bpf_map__set_value_size(b.bpfMap, C.uint(size))
 	if errC != 0 {
 		return fmt.Errorf("could not set map value size: %w", syscall.Errno(-errC))
@@ -674,26 +1006,53 @@ func (b *BPFMap) SetValueSize(size uint32) error {
 // should be taken as to take a reference to the first element
 // in the slice or array instead of the slice/array itself, as to
 // avoid undefined behavior.
+//
+// For per-CPU maps, it returns the value of CPU 0, see GetValuePerCPU.
 func (b *BPFMap) GetValue(key unsafe.Pointer) ([]byte, error) {
-	value := make([]byte, b.ValueSize())
+	value, err := b.makeValue()
+	if err != nil {
+		return nil, err
+	}
 	valuePtr := unsafe.Pointer(&value[0])
 
 	ret, errC := C.bpf_map_lookup_elem(b.fd, key, valuePtr)
 	if ret != 0 {
 		return nil, fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, errC)
 	}
-	return value, nil
+	return value[:b.ValueSize()], nil
 }
 
 func (b *BPFMap) GetValueFlags(key unsafe.Pointer, flags MapFlag) ([]byte, error) {
-	value := make([]byte, b.ValueSize())
+	value, err := b.makeValue()
+	if err != nil {
+		return nil, err
+	}
 	valuePtr := unsafe.Pointer(&value[0])
 
 	errC := C.bpf_map_lookup_elem_flags(b.fd, key, valuePtr, C.ulonglong(flags))
 	if errC != 0 {
 		return nil, fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, syscall.Errno(-errC))
 	}
-	return value, nil
+	return value[:b.ValueSize()], nil
+}
+
+// makeValue allocates room for a value of the map, which the kernel writes
+// for all CPUs at once for per-CPU maps
+func (b *BPFMap) makeValue() ([]byte, error) {
+	size, err := b.lookupSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup value in map %s: %w", b.name, err)
+	}
+	return make([]byte, size), nil
+}
+
+// lookupSize returns the size of a value as lookups write it: of all
+// possible CPUs for per-CPU maps
+func (b *BPFMap) lookupSize() (int, error) {
+	if !isPerCPU(b.Type()) {
+		return b.ValueSize(), nil
+	}
+	return b.perCPUSize()
 }
 
 // GetValueReadInto is like GetValue, except it allows the caller to pass in
@@ -703,7 +1062,28 @@ func (b *BPFMap) GetValueFlags(key unsafe.Pointer, flags MapFlag) ([]byte, error
 // per-cpu arrays and hash maps where the size of each value depends on the
 // number of CPUs
 func (b *BPFMap) GetValueReadInto(key unsafe.Pointer, value *[]byte) error {
+	if b.bpfMap == nil {
+		// bpf_map__lookup_elem checks the size for maps of an object
+		size, err := b.lookupSize()
+		if err != nil {
+			return fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, err)
+		}
+		if len(*value) != size {
+			return fmt.Errorf("failed to lookup value %v in map %s: value buffer of %d bytes, expected %d: %w",
+				key, b.name, len(*value), size, syscall.EINVAL)
+		}
+	} else if len(*value) == 0 {
+		return fmt.Errorf("failed to lookup value %v in map %s: empty value buffer: %w", key, b.name, syscall.EINVAL)
+	}
+
 	valuePtr := unsafe.Pointer(&(*value)[0])
+	if b.bpfMap == nil {
+		errC := C.bpf_map_lookup_elem(b.fd, key, valuePtr)
+		if errC != 0 {
+			return fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, syscall.Errno(-errC))
+		}
+		return nil
+	}
 	errC := C.bpf_map__lookup_elem(b.bpfMap, key, C.ulong(b.KeySize()), valuePtr, C.ulong(len(*value)), 0)
 	if errC != 0 {
 		return fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, syscall.Errno(-errC))
@@ -745,9 +1125,16 @@ func bpfMapBatchOptsToC(batchOpts *BPFMapBatchOpts) *C.struct_bpf_map_batch_opts
 //
 // The API can return partial results even though an error is returned.
 // In this case the keys that were successfully retrieved until an error occurred will be in the result slice.
+//
+// For per-CPU maps, the values are those of CPU 0, like GetValue (see GetValueBatchPerCPU).
 func (b *BPFMap) GetValueBatch(keys unsafe.Pointer, startKey, nextKey unsafe.Pointer, count uint32) ([][]byte, error) {
+	stride, err := b.lookupSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch lookup values in map %s: %w", b.name, err)
+	}
+
 	var (
-		values    = make([]byte, b.ValueSize()*int(count))
+		values    = make([]byte, stride*int(count))
 		valuesPtr = unsafe.Pointer(&values[0])
 		countC    = C.uint(count)
 	)
@@ -763,14 +1150,14 @@ func (b *BPFMap) GetValueBatch(keys unsafe.Pointer, startKey, nextKey unsafe.Poi
 		sc := syscall.Errno(-errC)
 		if sc != syscall.EFAULT {
 			if uint32(countC) != count {
-				return collectBatchValues(values, uint32(countC), b.ValueSize()),
+				return collectBatchValues(values, uint32(countC), stride, b.ValueSize()),
 					fmt.Errorf("failed to retrieve ALL elements in map %s, fetched (%d/%d): %w", b.name, uint32(countC), count, sc)
 			}
 		}
 		return nil, fmt.Errorf("failed to batch lookup values %v in map %s: %w", keys, b.name, syscall.Errno(-errC))
 	}
 
-	return collectBatchValues(values, count, b.ValueSize()), nil
+	return collectBatchValues(values, count, stride, b.ValueSize()), nil
 }
 
 // GetValueAndDeleteBatch allows for batch lookup and deletion of elements where each element is deleted after being retrieved from the map.
@@ -788,9 +1175,16 @@ func (b *BPFMap) GetValueBatch(keys unsafe.Pointer, startKey, nextKey unsafe.Poi
 //
 // The API can return partial results even though an -1 is returned. In this case, errno will be set to `ENOENT` and the values slice and count
 // will be filled in with the elements that were read. See the comment below for more context.
+//
+// For per-CPU maps, the values are those of CPU 0, like GetValue.
 func (b *BPFMap) GetValueAndDeleteBatch(keys, startKey, nextKey unsafe.Pointer, count uint32) ([][]byte, error) {
+	stride, err := b.lookupSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch lookup and delete values in map %s: %w", b.name, err)
+	}
+
 	var (
-		values    = make([]byte, b.ValueSize()*int(count))
+		values    = make([]byte, stride*int(count))
 		valuesPtr = unsafe.Pointer(&values[0])
 		countC    = C.uint(count)
 	)
@@ -831,14 +1225,16 @@ func (b *BPFMap) GetValueAndDeleteBatch(keys, startKey, nextKey unsafe.Pointer,
 	}
 
 	// Either some or all entries were read and deleted.
-	parsedVals := collectBatchValues(values, processed, b.ValueSize())
+	parsedVals := collectBatchValues(values, processed, stride, b.ValueSize())
 	return parsedVals, nil
 }
 
-func collectBatchValues(values []byte, count uint32, valueSize int) [][]byte {
+// collectBatchValues splits values, laid out every stride bytes, into count
+// values of valueSize bytes
+func collectBatchValues(values []byte, count uint32, stride, valueSize int) [][]byte {
 	var value []byte
 	var collected [][]byte
-	for i := 0; i < int(count*uint32(valueSize)); i += valueSize {
+	for i := 0; i < int(count)*stride; i += stride {
 		value = values[i : i+valueSize]
 		collected = append(collected, value)
 	}
@@ -1004,7 +1400,9 @@ func (it *BPFObjectIterator) NextProgram() *BPFProg {
 	return prog
 }
 
-// BPFMapIterator iterates over keys in a BPF map
+// BPFMapIterator iterates over keys in a BPF map. The walk restarts from
+// the first key if the current one is deleted; see BPFMapEntryIterator to
+// iterate over keys and values, deletion included.
 type BPFMapIterator struct {
 	b    *BPFMap
 	err  error
@@ -1075,6 +1473,9 @@ func (m *Module) GetProgram(progName string) (*BPFProg, error) {
 }
 
 func (p *BPFProg) GetFd() int {
+	if p.prog == nil {
+		return int(p.fd)
+	}
 	return int(C.bpf_program__fd(p.prog))
 }
 
@@ -1085,6 +1486,14 @@ func (p *BPFProg) Pin(path string) error {
 		return fmt.Errorf("invalid path: %s: %v", path, err)
 	}
 
+	if p.prog == nil {
+		if err := objPin(p.fd, absPath); err != nil {
+			return fmt.Errorf("failed to pin program %s to %s: %w", p.name, path, err)
+		}
+		p.pinnedPath = absPath
+		return nil
+	}
+
 	cs := C.CString(absPath)
 	ret, errC := C.bpf_program__pin(p.prog, cs)
 	C.free(unsafe.Pointer(cs))
@@ -1096,6 +1505,13 @@ func (p *BPFProg) Pin(path string) error {
 }
 
 func (p *BPFProg) Unpin(path string) error {
+	if p.prog == nil {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to unpin program %s to %s: %w", p.name, path, err)
+		}
+		p.pinnedPath = ""
+		return nil
+	}
 	cs := C.CString(path)
 	ret, errC := C.bpf_program__unpin(p.prog, cs)
 	C.free(unsafe.Pointer(cs))
@@ -1115,6 +1531,9 @@ func (p *BPFProg) GetName() string {
 }
 
 func (p *BPFProg) GetSectionName() string {
+	if p.prog == nil {
+		return ""
+	}
 	cs := C.bpf_program__section_name(p.prog)
 	gs := C.GoString(cs)
 	return gs
@@ -1253,10 +1672,23 @@ const (
 )
 
 func (p *BPFProg) GetType() BPFProgType {
+	if p.prog == nil {
+		return p.info.Type
+	}
 	return BPFProgType(C.bpf_program__get_type(p.prog))
 }
 
+func (p *BPFProg) autoload() bool {
+	if p.prog == nil {
+		return false
+	}
+	return bool(C.bpf_program__autoload(p.prog))
+}
+
 func (p *BPFProg) SetAutoload(autoload bool) error {
+	if p.prog == nil {
+		return fmt.Errorf("failed to set bpf program autoload: %w", syscall.EBUSY)
+	}
 	cbool := C.bool(autoload)
 	ret, errC := C.bpf_program__set_autoload(p.prog, cbool)
 	if ret != 0 {
@@ -1265,6 +1697,90 @@ func (p *BPFProg) SetAutoload(autoload bool) error {
 	return nil
 }
 
+// defaultKernelLogSize mirrors libbpf's own verifier log buffer size. Log
+// buffers are only backed by memory once the kernel writes into them.
+const defaultKernelLogSize = 0xffffffff >> 8
+
+// SetLogLevel sets the log level of the kernel verifier for this program.
+// Level 0 only keeps the log of a program that failed to load, while 1 and 2
+// log every instruction, and 4 adds verification statistics.
+// It must be called prior to BPFLoadObject.
+func (p *BPFProg) SetLogLevel(level uint32) error {
+	if p.prog == nil {
+		return fmt.Errorf("failed to set log level for program %s: %w", p.name, syscall.EBUSY)
+	}
+	errC := C.bpf_program__set_log_level(p.prog, C.uint(level))
+	if errC != 0 {
+		return fmt.Errorf("failed to set log level for program %s: %w", p.name, syscall.Errno(-errC))
+	}
+	return nil
+}
+
+func (p *BPFProg) GetLogLevel() uint32 {
+	if p.prog == nil {
+		return 0
+	}
+	return uint32(C.bpf_program__log_level(p.prog))
+}
+
+// SetLogSize gives this program a buffer of its own, of size bytes, for the
+// kernel verifier to write its log into. Programs with a log level get one
+// of NewModuleArgs.KernelLogSize bytes otherwise. Logs that do not fit make
+// the load fail with ENOSPC. It must be called prior to BPFLoadObject.
+func (p *BPFProg) SetLogSize(size uint32) error {
+	if size == 0 {
+		return fmt.Errorf("failed to set log size for program %s: %w", p.name, syscall.EINVAL)
+	}
+	if p.prog == nil {
+		return fmt.Errorf("failed to set log size for program %s: %w", p.name, syscall.EBUSY)
+	}
+
+	old := p.module.logBufs[p.prog]
+	if old != nil {
+		var oldSize C.size_t
+		C.bpf_program__log_buf(p.prog, &oldSize)
+		if oldSize == C.size_t(size) {
+			return nil
+		}
+	}
+
+	buf := C.calloc(C.size_t(size), 1)
+	if buf == nil {
+		return fmt.Errorf("failed to allocate log buffer for program %s", p.name)
+	}
+	errC := C.bpf_program__set_log_buf(p.prog, (*C.char)(buf), C.size_t(size))
+	if errC != 0 {
+		C.free(buf)
+		return fmt.Errorf("failed to set log size for program %s: %w", p.name, syscall.Errno(-errC))
+	}
+
+	if p.module.logBufs == nil {
+		p.module.logBufs = make(map[*C.struct_bpf_program]unsafe.Pointer)
+	}
+	p.module.logBufs[p.prog] = buf
+	C.free(old)
+	return nil
+}
+
+// GetLog returns the kernel verifier log of the program, as captured during
+// BPFLoadObject. Programs without a log level nor a log buffer of their own
+// (see SetLogSize) are only logged when rejected: their log is empty unless
+// BPFLoadObject failed on them.
+func (p *BPFProg) GetLog() string {
+	if p.prog == nil {
+		return ""
+	}
+	if p.module.logBufs[p.prog] == nil && p.module.failed != p.prog {
+		return ""
+	}
+	var size C.size_t
+	buf := C.bpf_program__log_buf(p.prog, &size)
+	if buf == nil {
+		return ""
+	}
+	return C.GoString(buf)
+}
+
 // AttachGeneric is used to attach the BPF program using autodetection
 // for the attach target. You can specify the destination in BPF code
 // via the SEC() such as `SEC("fentry/some_kernel_func")`
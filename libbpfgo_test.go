@@ -1,9 +1,12 @@
 package libbpfgo
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"syscall"
 	"testing"
+	"unsafe"
 )
 
 func Test_LoadAndAttach(t *testing.T) {
@@ -96,3 +99,62 @@ func Test_LoadAndAttach(t *testing.T) {
 		}
 	}
 }
+
+func TestGetValueReadIntoSize(t *testing.T) {
+	bpfMap := &BPFMap{name: "counts", fd: -1, info: &BPFMapInfo{Type: MapTypeHash, KeySize: 4, ValueSize: 8}}
+	key := uint32(0)
+
+	for _, size := range []int{0, 4} {
+		value := make([]byte, size)
+		if err := bpfMap.GetValueReadInto(unsafe.Pointer(&key), &value); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%d bytes buffer: expected EINVAL, got %v", size, err)
+		}
+	}
+}
+
+func TestStandaloneProgLogSettings(t *testing.T) {
+	prog := &BPFProg{name: "opened_by_id", fd: -1, info: &BPFProgInfo{}}
+
+	if err := prog.SetLogLevel(1); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("SetLogLevel: expected EBUSY, got %v", err)
+	}
+	if err := prog.SetLogSize(4096); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("SetLogSize: expected EBUSY, got %v", err)
+	}
+	if err := prog.SetAutoload(false); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("SetAutoload: expected EBUSY, got %v", err)
+	}
+	if prog.GetLogLevel() != 0 || prog.GetLog() != "" {
+		t.Errorf("unexpected log level or log for a program opened by ID")
+	}
+}
+
+func TestStandaloneLinkDestroy(t *testing.T) {
+	var fds [2]int
+	if err := syscall.Pipe(fds[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[1])
+
+	// the fd of the link is a C.int, unavailable to tests: move the read
+	// end of the pipe to a known number
+	const linkFD = 100
+	if err := syscall.Dup3(fds[0], linkFD, 0); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Close(fds[0])
+
+	link := &BPFLink{fd: linkFD, eventName: "opened_by_id"}
+	if err := link.Destroy(); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if link.GetFd() != -1 {
+		t.Errorf("fd %d not reset", link.GetFd())
+	}
+	if _, err := syscall.Write(fds[1], []byte{0}); !errors.Is(err, syscall.EPIPE) {
+		t.Errorf("read end of the pipe still open: %v", err)
+	}
+	if err := link.Destroy(); err != nil {
+		t.Errorf("second Destroy: %v", err)
+	}
+}
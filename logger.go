@@ -0,0 +1,151 @@
+package libbpfgo
+
+/*
+#include <bpf/libbpf.h>
+
+void set_print_fn();
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// LogLevel is the level of a message printed by libbpf
+type LogLevel int
+
+const (
+	LogLevelWarn  LogLevel = C.LIBBPF_WARN
+	LogLevelInfo  LogLevel = C.LIBBPF_INFO
+	LogLevelDebug LogLevel = C.LIBBPF_DEBUG
+)
+
+func (l LogLevel) String() string {
+	x := map[LogLevel]string{
+		LogLevelWarn:  "WARN",
+		LogLevelInfo:  "INFO",
+		LogLevelDebug: "DEBUG",
+	}
+	return x[l]
+}
+
+// LogFilter reports whether a libbpf message should be dropped. objName is
+// the name of the BPF object the message was printed for, and is empty if
+// it could not be told.
+type LogFilter func(level LogLevel, objName, msg string) bool
+
+// LogFilterExclusivityFlag drops the "Exclusivity flag on" warning libbpf
+// prints when probing tracing features.
+// BUG: https://github.com/aquasecurity/tracee/issues/1676
+func LogFilterExclusivityFlag(level LogLevel, objName, msg string) bool {
+	return strings.Contains(msg, "Exclusivity flag on")
+}
+
+type logger struct {
+	mu       sync.RWMutex
+	callback func(level LogLevel, objName, msg string)
+	filters  []LogFilter
+}
+
+var libbpfLogger = logger{
+	callback: defaultLoggerCallback,
+	filters:  []LogFilter{LogFilterExclusivityFlag},
+}
+
+// defaultLoggerCallback only prints warnings, to stderr
+func defaultLoggerCallback(level LogLevel, objName, msg string) {
+	if level == LogLevelWarn {
+		fmt.Fprint(os.Stderr, msg)
+	}
+}
+
+// SetLoggerCallback routes every message printed by libbpf (warnings, info
+// and debug) to cb, instead of printing warnings to stderr. Messages keep
+// libbpf's trailing newline. A nil cb restores the default behavior.
+func SetLoggerCallback(cb func(level LogLevel, msg string)) {
+	if cb == nil {
+		SetObjectLoggerCallback(nil)
+		return
+	}
+	SetObjectLoggerCallback(func(level LogLevel, objName, msg string) {
+		cb(level, msg)
+	})
+}
+
+// SetObjectLoggerCallback is like SetLoggerCallback, but also hands cb the
+// name of the BPF object that libbpf was opening or loading when it printed
+// the message, or an empty string if the message is not tied to an object.
+func SetObjectLoggerCallback(cb func(level LogLevel, objName, msg string)) {
+	libbpfLogger.mu.Lock()
+	defer libbpfLogger.mu.Unlock()
+
+	if cb == nil {
+		cb = defaultLoggerCallback
+	}
+	libbpfLogger.callback = cb
+	C.set_print_fn()
+}
+
+// SetLoggerFilters replaces the filters applied to libbpf messages before
+// they reach the logger callback. A message is dropped if any of the
+// filters returns true. Calling it with no filters disables filtering.
+func SetLoggerFilters(filters ...LogFilter) {
+	libbpfLogger.mu.Lock()
+	defer libbpfLogger.mu.Unlock()
+
+	libbpfLogger.filters = filters
+}
+
+func (l *logger) log(level LogLevel, objName, msg string) {
+	l.mu.RLock()
+	callback, filters := l.callback, l.filters
+	l.mu.RUnlock()
+
+	for _, filter := range filters {
+		if filter(level, objName, msg) {
+			return
+		}
+	}
+	callback(level, objName, msg)
+}
+
+// logObjNames maps the OS thread doing a libbpf call to the name of the
+// BPF object the call is made for. libbpf prints synchronously, so the
+// logger callback runs on that same thread.
+var logObjNames sync.Map
+
+// withLogObject runs fn, tagging everything libbpf prints meanwhile with
+// the given object name.
+func withLogObject(objName string, fn func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	tid := syscall.Gettid()
+	logObjNames.Store(tid, objName)
+	defer logObjNames.Delete(tid)
+
+	fn()
+}
+
+func currentLogObject() string {
+	objName, ok := logObjNames.Load(syscall.Gettid())
+	if !ok {
+		return ""
+	}
+	return objName.(string)
+}
+
+// objNameFromPath mimics how libbpf names an object after its file path
+func objNameFromPath(path string) string {
+	name := filepath.Base(path)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,55 @@
+package libbpfgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoggerCallbackAndFilters(t *testing.T) {
+	type message struct {
+		level   LogLevel
+		objName string
+		msg     string
+	}
+	var got []message
+
+	SetObjectLoggerCallback(func(level LogLevel, objName, msg string) {
+		got = append(got, message{level, objName, msg})
+	})
+	defer SetObjectLoggerCallback(nil)
+	defer SetLoggerFilters(LogFilterExclusivityFlag)
+
+	libbpfLogger.log(LogLevelDebug, "", "libbpf: loading object\n")
+	libbpfLogger.log(LogLevelWarn, "", "Exclusivity flag on, cannot modify\n")
+
+	SetLoggerFilters(func(level LogLevel, objName, msg string) bool {
+		return strings.HasPrefix(msg, "skip")
+	})
+	libbpfLogger.log(LogLevelWarn, "", "skip me\n")
+	withLogObject("main", func() {
+		libbpfLogger.log(LogLevelInfo, currentLogObject(), "tagged\n")
+	})
+
+	expected := []message{
+		{LogLevelDebug, "", "libbpf: loading object\n"},
+		{LogLevelInfo, "main", "tagged\n"},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d messages, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("message %d: expected %v, got %v", i, expected[i], got[i])
+		}
+	}
+
+	if currentLogObject() != "" {
+		t.Errorf("object name leaked out of withLogObject")
+	}
+}
+
+func TestObjNameFromPath(t *testing.T) {
+	if name := objNameFromPath("/tmp/selftest/main.bpf.o"); name != "main" {
+		t.Errorf("expected main, got %s", name)
+	}
+}
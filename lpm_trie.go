@@ -0,0 +1,266 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"github.com/aquasecurity/libbpfgo/helpers"
+)
+
+// LPMTrie wraps an LPM trie map keyed by IPv4 or IPv6 prefixes, laid out as
+//
+//	struct key {
+//	    u32 prefixlen; // native endianness
+//	    u8  addr[4];   // or addr[16], network byte order
+//	};
+//
+// IPv4 tries take IPv4 and IPv4-mapped IPv6 prefixes, IPv6 tries IPv6
+// prefixes.
+type LPMTrie struct {
+	bpfMap *BPFMap
+	bits   int // 32 or 128
+}
+
+// NewLPMTrie wraps bpfMap, checking it is an LPM trie with keys of IPv4 or
+// IPv6 prefixes
+func NewLPMTrie(bpfMap *BPFMap) (*LPMTrie, error) {
+	if t := bpfMap.Type(); t != MapTypeLPMTrie {
+		return nil, fmt.Errorf("map %s is not an LPM trie: %s: %w", bpfMap.name, t, syscall.EINVAL)
+	}
+
+	switch bpfMap.KeySize() {
+	case 4 + net.IPv4len:
+		return &LPMTrie{bpfMap: bpfMap, bits: 32}, nil
+	case 4 + net.IPv6len:
+		return &LPMTrie{bpfMap: bpfMap, bits: 128}, nil
+	}
+	return nil, fmt.Errorf("LPM trie %s has key size %d, expected %d (IPv4) or %d (IPv6): %w",
+		bpfMap.name, bpfMap.KeySize(), 4+net.IPv4len, 4+net.IPv6len, syscall.EINVAL)
+}
+
+// Map returns the underlying map
+func (t *LPMTrie) Map() *BPFMap {
+	return t.bpfMap
+}
+
+// key encodes prefix, masked, as a key of the trie
+func (t *LPMTrie) key(prefix netip.Prefix) ([]byte, error) {
+	if !prefix.IsValid() {
+		return nil, fmt.Errorf("invalid prefix %s: %w", prefix, syscall.EINVAL)
+	}
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if t.bits == 32 && addr.Is4In6() {
+		if bits < 96 {
+			return nil, fmt.Errorf("prefix %s is not an IPv4 prefix: %w", prefix, syscall.EINVAL)
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	if addr.BitLen() != t.bits {
+		return nil, fmt.Errorf("prefix %s does not match the %d-bit addresses of LPM trie %s: %w", prefix, t.bits, t.bpfMap.name, syscall.EINVAL)
+	}
+
+	key := make([]byte, 4, 4+t.bits/8)
+	nativeEndian.PutUint32(key, uint32(bits))
+	return append(key, netip.PrefixFrom(addr, bits).Masked().Addr().AsSlice()...), nil
+}
+
+// prefix decodes a key of the trie
+func (t *LPMTrie) prefix(key []byte) (netip.Prefix, error) {
+	bits := int(nativeEndian.Uint32(key))
+	addr, _ := netip.AddrFromSlice(key[4:])
+	if bits > t.bits {
+		return netip.Prefix{}, fmt.Errorf("invalid key %s of LPM trie %s", lpmKeyString(key), t.bpfMap.name)
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
+
+// lpmKeyString formats a key of an LPM trie as address/prefixlen
+func lpmKeyString(key []byte) string {
+	addr := key[4:]
+	prefixlen := nativeEndian.Uint32(key)
+	if len(addr) == net.IPv4len {
+		return fmt.Sprintf("%s/%d", helpers.ParseUint32IP(binary.BigEndian.Uint32(addr)), prefixlen)
+	}
+	return fmt.Sprintf("%s/%d", helpers.Parse16BytesSliceIP(addr), prefixlen)
+}
+
+// prefixFromIPNet converts ipnet, keeping IPv4 addresses as such
+func prefixFromIPNet(ipnet *net.IPNet) (netip.Prefix, error) {
+	ip := ipnet.IP
+	switch len(ipnet.Mask) {
+	case net.IPv4len:
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+	case net.IPv6len:
+		ip = ip.To16()
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	ones, bits := ipnet.Mask.Size()
+	if !ok || bits != addr.BitLen() {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix %s: %w", ipnet, syscall.EINVAL)
+	}
+	return netip.PrefixFrom(addr, ones), nil
+}
+
+// Insert associates prefix with value, replacing the value of prefix if
+// already in the trie. The host bits of prefix are ignored.
+func (t *LPMTrie) Insert(prefix netip.Prefix, value unsafe.Pointer) error {
+	key, err := t.key(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to insert into LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.bpfMap.Update(unsafe.Pointer(&key[0]), value)
+}
+
+func (t *LPMTrie) InsertIPNet(ipnet *net.IPNet, value unsafe.Pointer) error {
+	prefix, err := prefixFromIPNet(ipnet)
+	if err != nil {
+		return fmt.Errorf("failed to insert into LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.Insert(prefix, value)
+}
+
+// Delete removes prefix, exactly, from the trie
+func (t *LPMTrie) Delete(prefix netip.Prefix) error {
+	key, err := t.key(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to delete from LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.bpfMap.DeleteKey(unsafe.Pointer(&key[0]))
+}
+
+func (t *LPMTrie) DeleteIPNet(ipnet *net.IPNet) error {
+	prefix, err := prefixFromIPNet(ipnet)
+	if err != nil {
+		return fmt.Errorf("failed to delete from LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.Delete(prefix)
+}
+
+// Lookup returns the value of prefix, exactly: a shorter prefix containing
+// it does not match. It fails with ENOENT if prefix is not in the trie.
+func (t *LPMTrie) Lookup(prefix netip.Prefix) ([]byte, error) {
+	key, err := t.key(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup LPM trie %s: %w", t.bpfMap.name, err)
+	}
+
+	exists, err := t.exists(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to lookup %s in LPM trie %s: %w", prefix, t.bpfMap.name, syscall.ENOENT)
+	}
+	// the longest match of prefix is prefix itself
+	return t.bpfMap.GetValue(unsafe.Pointer(&key[0]))
+}
+
+func (t *LPMTrie) LookupIPNet(ipnet *net.IPNet) ([]byte, error) {
+	prefix, err := prefixFromIPNet(ipnet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.Lookup(prefix)
+}
+
+// exists tells whether key is in the trie. Lookups match the longest
+// prefix, but get_next_key only continues after a key in the trie: from
+// any other key, it restarts from the first one.
+func (t *LPMTrie) exists(key []byte) (bool, error) {
+	first := make([]byte, len(key))
+	errC := C.bpf_map_get_next_key(t.bpfMap.fd, nil, unsafe.Pointer(&first[0]))
+	if errC != 0 {
+		if errno := syscall.Errno(-errC); errno != syscall.ENOENT {
+			return false, fmt.Errorf("failed to get first key of LPM trie %s: %w", t.bpfMap.name, errno)
+		}
+		return false, nil // empty
+	}
+
+	next := make([]byte, len(key))
+	errC = C.bpf_map_get_next_key(t.bpfMap.fd, unsafe.Pointer(&key[0]), unsafe.Pointer(&next[0]))
+	if errC != 0 {
+		if errno := syscall.Errno(-errC); errno != syscall.ENOENT {
+			return false, fmt.Errorf("failed to get next key of LPM trie %s: %w", t.bpfMap.name, errno)
+		}
+		return true, nil // key is the last one
+	}
+	return !bytes.Equal(next, first), nil
+}
+
+// LongestMatch returns the value of the longest prefix containing addr. It
+// fails with ENOENT if no prefix contains it.
+func (t *LPMTrie) LongestMatch(addr netip.Addr) ([]byte, error) {
+	key, err := t.key(netip.PrefixFrom(addr, addr.BitLen()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup LPM trie %s: %w", t.bpfMap.name, err)
+	}
+	return t.bpfMap.GetValue(unsafe.Pointer(&key[0]))
+}
+
+func (t *LPMTrie) LongestMatchIP(ip net.IP) ([]byte, error) {
+	if ip4 := ip.To4(); ip4 != nil && t.bits == 32 {
+		ip = ip4
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil, fmt.Errorf("failed to lookup LPM trie %s: invalid address %s: %w", t.bpfMap.name, ip, syscall.EINVAL)
+	}
+	return t.LongestMatch(addr)
+}
+
+// LPMTrieIterator iterates over the prefixes and values of an LPM trie,
+// see BPFMapEntryIterator for what happens to prefixes deleted while
+// iterating
+type LPMTrieIterator struct {
+	t      *LPMTrie
+	it     *BPFMapEntryIterator
+	prefix netip.Prefix
+	err    error
+}
+
+func (t *LPMTrie) Iterator() *LPMTrieIterator {
+	return &LPMTrieIterator{
+		t:  t,
+		it: t.bpfMap.EntryIterator(0),
+	}
+}
+
+func (it *LPMTrieIterator) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		return false
+	}
+	it.prefix, it.err = it.t.prefix(it.it.Key())
+	return it.err == nil
+}
+
+// Prefix returns the current prefix, if the most recent call to Next
+// returned true
+func (it *LPMTrieIterator) Prefix() netip.Prefix {
+	return it.prefix
+}
+
+// Value returns the value of the current prefix. The slice is valid only
+// until the next call to Next.
+func (it *LPMTrieIterator) Value() []byte {
+	return it.it.Value()
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *LPMTrieIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
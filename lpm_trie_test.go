@@ -0,0 +1,90 @@
+package libbpfgo
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func lpmKey(prefixlen uint32, addr ...byte) []byte {
+	key := make([]byte, 4)
+	nativeEndian.PutUint32(key, prefixlen)
+	return append(key, addr...)
+}
+
+func TestLPMTrieKey(t *testing.T) {
+	v4 := &LPMTrie{bpfMap: &BPFMap{name: "v4"}, bits: 32}
+	v6 := &LPMTrie{bpfMap: &BPFMap{name: "v6"}, bits: 128}
+
+	testCases := []struct {
+		trie     *LPMTrie
+		prefix   string
+		expected []byte
+		decoded  string // prefix decoded from the key
+	}{
+		{v4, "10.1.2.3/8", lpmKey(8, 10, 0, 0, 0), "10.0.0.0/8"},
+		{v4, "192.168.1.1/32", lpmKey(32, 192, 168, 1, 1), "192.168.1.1/32"},
+		{v4, "::ffff:10.0.0.0/104", lpmKey(8, 10, 0, 0, 0), "10.0.0.0/8"},
+		{v6, "2001:db8::1/32", lpmKey(32, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0), "2001:db8::/32"},
+		{v4, "2001:db8::/32", nil, ""},
+		{v6, "10.0.0.0/8", nil, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			key, err := tc.trie.key(netip.MustParsePrefix(tc.prefix))
+			if tc.expected == nil {
+				if err == nil {
+					t.Errorf("expected an error, got key %v", key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, tc.expected) {
+				t.Errorf("expected key %v, got %v", tc.expected, key)
+			}
+
+			prefix, err := tc.trie.prefix(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prefix.String() != tc.decoded {
+				t.Errorf("expected prefix %s, got %s", tc.decoded, prefix)
+			}
+		})
+	}
+
+	if _, err := v4.prefix(lpmKey(33, 10, 0, 0, 0)); err == nil {
+		t.Error("expected an error for a prefix length above 32")
+	}
+	if s := lpmKeyString(lpmKey(24, 10, 1, 2, 0)); s != "10.1.2.0/24" {
+		t.Errorf("expected 10.1.2.0/24, got %s", s)
+	}
+}
+
+func TestPrefixFromIPNet(t *testing.T) {
+	for _, s := range []string{"10.0.0.0/8", "2001:db8::/32"} {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix, err := prefixFromIPNet(ipnet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefix.String() != s {
+			t.Errorf("expected %s, got %s", s, prefix)
+		}
+	}
+
+	// 16-byte IPv4 address, as returned by net.ParseIP
+	prefix, err := prefixFromIPNet(&net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, 32)})
+	if err != nil || prefix.String() != "10.0.0.0/8" {
+		t.Errorf("expected 10.0.0.0/8, got %s (%v)", prefix, err)
+	}
+	if _, err = prefixFromIPNet(&net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(8, 32)}); err == nil {
+		t.Error("expected an error for an IPv6 address with an IPv4 mask")
+	}
+}
@@ -0,0 +1,276 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// DefaultEntryChunkSize is the number of entries EntryIterator reads per
+// chunk, when none is given
+const DefaultEntryChunkSize = 1024
+
+// ErrIterationAborted is returned by BPFMapEntryIterator.Err when a walk
+// with get_next_key visited more keys than the map can hold: concurrent
+// deletions made the kernel restart it from the first key.
+var ErrIterationAborted = errors.New("iteration aborted: map modified concurrently")
+
+// errENOTSUPP is the kernel internal ENOTSUPP, returned for map types
+// without batch operations
+const errENOTSUPP = syscall.Errno(524)
+
+// batchTokenSize returns the size of the opaque batch token of the map:
+// a key for arrays, a bucket for hashes
+func (b *BPFMap) batchTokenSize() int {
+	if b.KeySize() < 4 {
+		return 4
+	}
+	return b.KeySize()
+}
+
+// BPFMapEntryIterator iterates over the keys and values of a BPF map, a
+// chunk of entries at a time. Chunks are read with BPF_MAP_LOOKUP_BATCH,
+// or with BPF_MAP_GET_NEXT_KEY and BPF_MAP_LOOKUP_ELEM on kernels and map
+// types without batch operations. Buffers are allocated once and reused
+// across chunks and walks (see Reset).
+//
+// Entries can be deleted while iterating, the current one included:
+//   - With batches (hashes, arrays), the kernel walks the map by bucket or
+//     index. An entry deleted before its chunk is read is not returned.
+//   - With get_next_key, the key following the current one is read before
+//     the current one is returned. An entry deleted before it is reached is
+//     skipped. If the key the walk stands on is deleted by someone else,
+//     the kernel restarts it from the first key, returning entries twice:
+//     the walk is aborted with ErrIterationAborted once it visited more keys
+//     than the map can hold.
+//
+// Entries added while iterating may or may not be returned.
+type BPFMapEntryIterator struct {
+	b         *BPFMap
+	chunkSize uint32
+	stride    int // size of a value in the buffer, of all CPUs for per-CPU maps
+
+	noBatch  bool // batch operations are not supported
+	started  bool
+	done     bool
+	inBatch  []byte
+	outBatch []byte
+	cursor   []byte // next key of the get_next_key walk
+	visited  uint32
+
+	keys   []byte
+	values []byte
+	count  int // entries in the current chunk
+	pos    int
+	err    error
+}
+
+// EntryIterator returns an iterator reading chunkSize entries at a time,
+// or DefaultEntryChunkSize if 0
+func (b *BPFMap) EntryIterator(chunkSize uint32) *BPFMapEntryIterator {
+	if chunkSize == 0 {
+		chunkSize = DefaultEntryChunkSize
+	}
+	return &BPFMapEntryIterator{
+		b:         b,
+		chunkSize: chunkSize,
+		pos:       -1,
+	}
+}
+
+// Reset rewinds the iterator to the beginning of the map, keeping its
+// buffers
+func (it *BPFMapEntryIterator) Reset() {
+	it.started = false
+	it.done = false
+	it.visited = 0
+	it.count = 0
+	it.pos = -1
+	it.err = nil
+}
+
+func (it *BPFMapEntryIterator) alloc() error {
+	if it.b.KeySize() == 0 {
+		// queues, stacks and bloom filters have no keys to walk
+		return fmt.Errorf("failed to iterate over map %s: %s has no keys: %w", it.b.name, it.b.Type(), syscall.EINVAL)
+	}
+	it.stride = it.b.ValueSize()
+	if isPerCPU(it.b.Type()) {
+		size, err := it.b.perCPUSize()
+		if err != nil {
+			return err
+		}
+		it.stride = size
+	}
+	it.keys = make([]byte, int(it.chunkSize)*it.b.KeySize())
+	it.values = make([]byte, int(it.chunkSize)*it.stride)
+	it.inBatch = make([]byte, it.b.batchTokenSize())
+	it.outBatch = make([]byte, it.b.batchTokenSize())
+	it.cursor = make([]byte, it.b.KeySize())
+	return nil
+}
+
+// Next advances to the next entry. It returns false at the end of the map
+// or on error, see Err.
+func (it *BPFMapEntryIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.pos++
+	for it.pos >= it.count {
+		if it.done {
+			return false
+		}
+		if it.keys == nil {
+			if it.err = it.alloc(); it.err != nil {
+				return false
+			}
+		}
+		if it.err = it.fill(); it.err != nil {
+			return false
+		}
+		it.pos = 0
+	}
+	return true
+}
+
+func (it *BPFMapEntryIterator) fill() error {
+	if !it.noBatch {
+		err := it.fillBatch()
+		if !it.noBatch {
+			return err
+		}
+	}
+	return it.fillNextKey()
+}
+
+// fillBatch reads the next chunk with BPF_MAP_LOOKUP_BATCH. It sets noBatch
+// if the first one fails as unsupported.
+func (it *BPFMapEntryIterator) fillBatch() error {
+	opts := &BPFMapBatchOpts{
+		Sz:        uint64(unsafe.Sizeof(BPFMapBatchOpts{})),
+		ElemFlags: C.BPF_ANY,
+		Flags:     C.BPF_ANY,
+	}
+
+	for {
+		var inBatch unsafe.Pointer
+		if it.started {
+			inBatch = unsafe.Pointer(&it.inBatch[0])
+		}
+		countC := C.uint(it.chunkSize)
+
+		errC := C.bpf_map_lookup_batch(it.b.fd, inBatch, unsafe.Pointer(&it.outBatch[0]),
+			unsafe.Pointer(&it.keys[0]), unsafe.Pointer(&it.values[0]), &countC, bpfMapBatchOptsToC(opts))
+		if errC != 0 {
+			errno := syscall.Errno(-errC)
+			switch {
+			case errno == syscall.ENOENT:
+				// end of the map, with the last entries
+				it.done = true
+			case errno == syscall.ENOSPC && countC == 0:
+				// a bucket holds more entries than a chunk
+				it.chunkSize *= 2
+				it.keys = make([]byte, int(it.chunkSize)*it.b.KeySize())
+				it.values = make([]byte, int(it.chunkSize)*it.stride)
+				continue
+			case !it.started && (errno == syscall.EINVAL || errno == errENOTSUPP):
+				it.noBatch = true
+				return nil
+			default:
+				return fmt.Errorf("failed to batch lookup entries of map %s: %w", it.b.name, errno)
+			}
+		}
+
+		it.started = true
+		it.inBatch, it.outBatch = it.outBatch, it.inBatch
+		it.count = int(countC)
+		return nil
+	}
+}
+
+// fillNextKey reads the next chunk with BPF_MAP_GET_NEXT_KEY and
+// BPF_MAP_LOOKUP_ELEM
+func (it *BPFMapEntryIterator) fillNextKey() error {
+	keySize := it.b.KeySize()
+	maxEntries := uint32(it.b.GetMaxEntries())
+
+	if !it.started {
+		errC := C.bpf_map_get_next_key(it.b.fd, nil, unsafe.Pointer(&it.cursor[0]))
+		if errC != 0 {
+			if errno := syscall.Errno(-errC); errno != syscall.ENOENT {
+				return fmt.Errorf("failed to get first key of map %s: %w", it.b.name, errno)
+			}
+			it.done = true
+		}
+		it.started = true
+	}
+
+	it.count = 0
+	for it.count < int(it.chunkSize) && !it.done {
+		if maxEntries > 0 && it.visited >= maxEntries {
+			return fmt.Errorf("failed to iterate over map %s: %w", it.b.name, ErrIterationAborted)
+		}
+		it.visited++
+
+		key := it.keys[it.count*keySize : (it.count+1)*keySize]
+		value := it.values[it.count*it.stride : (it.count+1)*it.stride]
+		copy(key, it.cursor)
+
+		// get the next key first, so that key can be deleted once returned
+		errC := C.bpf_map_get_next_key(it.b.fd, unsafe.Pointer(&key[0]), unsafe.Pointer(&it.cursor[0]))
+		if errC != 0 {
+			if errno := syscall.Errno(-errC); errno != syscall.ENOENT {
+				return fmt.Errorf("failed to get next key of map %s: %w", it.b.name, errno)
+			}
+			it.done = true
+		}
+
+		errC = C.bpf_map_lookup_elem(it.b.fd, unsafe.Pointer(&key[0]), unsafe.Pointer(&value[0]))
+		if errC != 0 {
+			if errno := syscall.Errno(-errC); errno != syscall.ENOENT {
+				return fmt.Errorf("failed to lookup entry of map %s: %w", it.b.name, errno)
+			}
+			continue // deleted since
+		}
+		it.count++
+	}
+	return nil
+}
+
+// Key returns the key of the current entry, if the most recent call to Next
+// returned true. The slice is valid only until the next call to Next.
+func (it *BPFMapEntryIterator) Key() []byte {
+	size := it.b.KeySize()
+	return it.keys[it.pos*size : (it.pos+1)*size]
+}
+
+// Value returns the value of the current entry, of CPU 0 for per-CPU maps.
+// The slice is valid only until the next call to Next.
+func (it *BPFMapEntryIterator) Value() []byte {
+	start := it.pos * it.stride
+	return it.values[start : start+it.b.ValueSize()]
+}
+
+// ValuePerCPU returns the values of the current entry of a per-CPU map,
+// indexed by CPU number, or nil for other maps. The slices are valid only
+// until the next call to Next.
+func (it *BPFMapEntryIterator) ValuePerCPU() [][]byte {
+	if !isPerCPU(it.b.Type()) {
+		return nil
+	}
+	start := it.pos * it.stride
+	return it.b.splitPerCPU(it.values[start : start+it.stride])
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *BPFMapEntryIterator) Err() error {
+	return it.err
+}
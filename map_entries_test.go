@@ -0,0 +1,21 @@
+package libbpfgo
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestEntryIteratorKeylessMap(t *testing.T) {
+	for _, mapType := range []MapType{MapTypeQueue, MapTypeStack, MapTypeBloomFilter} {
+		bpfMap := &BPFMap{name: "events", fd: -1, info: &BPFMapInfo{Type: mapType, ValueSize: 8, MaxEntries: 16}}
+
+		it := bpfMap.EntryIterator(0)
+		if it.Next() {
+			t.Fatalf("%s: Next returned an entry", mapType)
+		}
+		if err := it.Err(); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: expected EINVAL, got %v", mapType, err)
+		}
+	}
+}
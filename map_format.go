@@ -0,0 +1,197 @@
+package libbpfgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aquasecurity/libbpfgo/btf"
+)
+
+// EntryFormatter decodes the keys and values of a map with the types of
+// its BTF, see btf.MarshalValue. Keys and values without BTF type are
+// rendered as arrays of hex bytes, as bpftool does.
+type EntryFormatter struct {
+	b         *BPFMap
+	keyType   btf.Type
+	valueType btf.Type
+}
+
+// EntryFormatter loads the BTF of the map
+func (b *BPFMap) EntryFormatter() (*EntryFormatter, error) {
+	info, err := b.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BTF of map %s: %w", b.name, err)
+	}
+
+	f := &EntryFormatter{b: b}
+	if info.BTFID == 0 {
+		return f, nil
+	}
+	spec, err := LoadBTFByID(info.BTFID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BTF of map %s: %w", b.name, err)
+	}
+	if f.keyType, err = mapBTFType(spec, info.BTFKeyTypeID, info.KeySize); err != nil {
+		return nil, fmt.Errorf("failed to get BTF of map %s key: %w", b.name, err)
+	}
+	if f.valueType, err = mapBTFType(spec, info.BTFValueTypeID, info.ValueSize); err != nil {
+		return nil, fmt.Errorf("failed to get BTF of map %s value: %w", b.name, err)
+	}
+	return f, nil
+}
+
+// mapBTFType returns the type id, nil if 0 (no type)
+func mapBTFType(spec *btf.Spec, id, size uint32) (btf.Type, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	t, err := spec.TypeByID(btf.TypeID(id))
+	if err != nil {
+		return nil, err
+	}
+	typeSize, err := btf.Sizeof(t)
+	if err != nil {
+		return nil, err
+	}
+	if typeSize != size {
+		return nil, fmt.Errorf("%s %s has size %d, expected %d", t.Kind(), t.Name(), typeSize, size)
+	}
+	return t, nil
+}
+
+func (f *EntryFormatter) marshal(t btf.Type, data []byte) ([]byte, error) {
+	if t != nil {
+		return btf.MarshalValue(t, data, nativeEndian)
+	}
+	hexBytes := make([]string, len(data))
+	for i, c := range data {
+		hexBytes[i] = fmt.Sprintf("0x%02x", c)
+	}
+	return json.Marshal(hexBytes)
+}
+
+// MarshalEntry encodes an entry as a JSON object, of fields "key" and
+// "value"
+func (f *EntryFormatter) MarshalEntry(key, value []byte) ([]byte, error) {
+	k, err := f.marshal(f.keyType, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format key of map %s: %w", f.b.name, err)
+	}
+	v, err := f.marshal(f.valueType, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format value of map %s: %w", f.b.name, err)
+	}
+	return []byte(fmt.Sprintf(`{"key":%s,"value":%s}`, k, v)), nil
+}
+
+// MarshalEntryPerCPU encodes an entry of a per-CPU map as a JSON object, of
+// fields "key" and "values", an array of objects of fields "cpu" and
+// "value"
+func (f *EntryFormatter) MarshalEntryPerCPU(key []byte, values [][]byte) ([]byte, error) {
+	k, err := f.marshal(f.keyType, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format key of map %s: %w", f.b.name, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"key":%s,"values":[`, k)
+	for cpu, value := range values {
+		v, err := f.marshal(f.valueType, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format value of map %s: %w", f.b.name, err)
+		}
+		if cpu > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"cpu":%d,"value":%s}`, cpu, v)
+	}
+	buf.WriteString("]}")
+	return buf.Bytes(), nil
+}
+
+// FormatEntry decodes an entry of the map with its BTF, as indented JSON.
+// The BTF of the map is loaded on the first call. For per-CPU maps, value
+// is the value of one CPU.
+//
+// For example, for a map of struct key { u32 pid; char comm[16]; } to u64:
+//
+//	{
+//	  "key": {
+//	    "pid": 1234,
+//	    "comm": "bash"
+//	  },
+//	  "value": 42
+//	}
+func (b *BPFMap) FormatEntry(key, value []byte) (string, error) {
+	if b.formatter == nil {
+		f, err := b.EntryFormatter()
+		if err != nil {
+			return "", err
+		}
+		b.formatter = f
+	}
+
+	entry, err := b.formatter.MarshalEntry(key, value)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, entry, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// MapJSONEncoder writes entries of a map as JSON, one object per line (see
+// EntryFormatter.MarshalEntry)
+type MapJSONEncoder struct {
+	w io.Writer
+	f *EntryFormatter
+}
+
+// NewMapJSONEncoder loads the BTF of the map, to encode its entries to w
+func NewMapJSONEncoder(w io.Writer, b *BPFMap) (*MapJSONEncoder, error) {
+	f, err := b.EntryFormatter()
+	if err != nil {
+		return nil, err
+	}
+	return &MapJSONEncoder{w: w, f: f}, nil
+}
+
+func (e *MapJSONEncoder) write(entry []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(entry, '\n'))
+	return err
+}
+
+func (e *MapJSONEncoder) Encode(key, value []byte) error {
+	return e.write(e.f.MarshalEntry(key, value))
+}
+
+func (e *MapJSONEncoder) EncodePerCPU(key []byte, values [][]byte) error {
+	return e.write(e.f.MarshalEntryPerCPU(key, values))
+}
+
+// EncodeMap encodes every entry of the map, with their values of all CPUs
+// for per-CPU maps
+func (e *MapJSONEncoder) EncodeMap() error {
+	perCPU := isPerCPU(e.f.b.Type())
+
+	it := e.f.b.EntryIterator(0)
+	for it.Next() {
+		var err error
+		if perCPU {
+			err = e.EncodePerCPU(it.Key(), it.ValuePerCPU())
+		} else {
+			err = e.Encode(it.Key(), it.Value())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
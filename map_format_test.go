@@ -0,0 +1,43 @@
+package libbpfgo
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/libbpfgo/btf"
+)
+
+func TestEntryFormatter(t *testing.T) {
+	spec, err := btf.LoadSpec("btf/testdata/vmlinux.btf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pidType, err := spec.FindEnum("pid_type")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a key without BTF, a value of enum pid_type
+	f := &EntryFormatter{b: &BPFMap{name: "test"}, valueType: pidType}
+	value := make([]byte, 4)
+	nativeEndian.PutUint32(value, 1)
+
+	entry, err := f.MarshalEntry([]byte{1, 0xff}, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"key":["0x01","0xff"],"value":"PIDTYPE_TGID"}`; string(entry) != expected {
+		t.Errorf("expected %s, got %s", expected, entry)
+	}
+
+	entry, err = f.MarshalEntryPerCPU([]byte{1, 0xff}, [][]byte{value, {0, 0, 0, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"key":["0x01","0xff"],"values":[{"cpu":0,"value":"PIDTYPE_TGID"},{"cpu":1,"value":"PIDTYPE_PID"}]}`; string(entry) != expected {
+		t.Errorf("expected %s, got %s", expected, entry)
+	}
+
+	if _, err = f.MarshalEntry([]byte{1}, []byte{1}); err == nil {
+		t.Error("expected an error for a value shorter than its type")
+	}
+}
@@ -0,0 +1,140 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+#include <bpf/libbpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const bpfFInnerMap = C.BPF_F_INNER_MAP
+
+func isMapOfMaps(mapType MapType) bool {
+	return mapType == MapTypeArrayOfMaps || mapType == MapTypeHashOfMaps
+}
+
+// SetInnerMap sets the template of the inner maps of an array or hash of
+// maps. It should be called prior to loading the module with
+// BPFLoadObject, on maps declared without their inner map (no
+// __array(values, ...)). template must be created, with CreateMap for
+// instance, and can be closed once the module is loaded.
+func (b *BPFMap) SetInnerMap(template *BPFMap) error {
+	if b.bpfMap == nil {
+		return fmt.Errorf("failed to set inner map of map %s: %w", b.name, syscall.EBUSY)
+	}
+	if !isMapOfMaps(b.Type()) {
+		return fmt.Errorf("failed to set inner map of map %s: %s is not a map of maps: %w", b.name, b.Type(), syscall.EINVAL)
+	}
+
+	info, err := template.Info()
+	if err != nil {
+		return fmt.Errorf("failed to set inner map of map %s: %w", b.name, err)
+	}
+	errC := C.bpf_map__set_inner_map_fd(b.bpfMap, C.int(template.GetFd()))
+	if errC != 0 {
+		return fmt.Errorf("failed to set inner map of map %s: %w", b.name, syscall.Errno(-errC))
+	}
+
+	if b.module.innerMaps == nil {
+		b.module.innerMaps = make(map[string]*BPFMapInfo)
+	}
+	b.module.innerMaps[b.name] = info
+	return nil
+}
+
+// innerMapTemplate returns the definition inner maps must match, or nil if
+// unknown (maps opened by ID or pinned)
+func (b *BPFMap) innerMapTemplate() *BPFMapInfo {
+	if b.bpfMap == nil {
+		return b.innerMap
+	}
+	if info, ok := b.module.innerMaps[b.name]; ok {
+		return info
+	}
+
+	inner := C.bpf_map__inner_map(b.bpfMap)
+	if inner == nil {
+		return nil
+	}
+	return &BPFMapInfo{
+		Type:       MapType(C.bpf_map__type(inner)),
+		Name:       C.GoString(C.bpf_map__name(inner)),
+		KeySize:    uint32(C.bpf_map__key_size(inner)),
+		ValueSize:  uint32(C.bpf_map__value_size(inner)),
+		MaxEntries: uint32(C.bpf_map__max_entries(inner)),
+		MapFlags:   uint32(C.bpf_map__map_flags(inner)),
+	}
+}
+
+// checkInnerMap mirrors the checks of the kernel (bpf_map_meta_equal), to
+// fail with a meaningful error rather than EINVAL
+func checkInnerMap(template, inner *BPFMapInfo) error {
+	switch {
+	case inner.Type != template.Type:
+		return fmt.Errorf("type %s, expected %s", inner.Type, template.Type)
+	case inner.KeySize != template.KeySize:
+		return fmt.Errorf("key size %d, expected %d", inner.KeySize, template.KeySize)
+	case inner.ValueSize != template.ValueSize:
+		return fmt.Errorf("value size %d, expected %d", inner.ValueSize, template.ValueSize)
+	case inner.MapFlags != template.MapFlags:
+		return fmt.Errorf("flags %#x, expected %#x", inner.MapFlags, template.MapFlags)
+	}
+
+	// arrays are inlined by the verifier, unless created with BPF_F_INNER_MAP
+	if inner.Type == MapTypeArray || inner.Type == MapTypePerCPUArray {
+		if template.MapFlags&bpfFInnerMap == 0 && inner.MaxEntries != template.MaxEntries {
+			return fmt.Errorf("max entries %d, expected %d", inner.MaxEntries, template.MaxEntries)
+		}
+	}
+	return nil
+}
+
+// SetInnerMapByKey stores inner in the map of maps at key, after checking
+// it is compatible with the inner map template
+func (b *BPFMap) SetInnerMapByKey(key unsafe.Pointer, inner *BPFMap) error {
+	if !isMapOfMaps(b.Type()) {
+		return fmt.Errorf("failed to set inner map of map %s: %s is not a map of maps: %w", b.name, b.Type(), syscall.EINVAL)
+	}
+
+	if template := b.innerMapTemplate(); template != nil {
+		info, err := inner.Info()
+		if err != nil {
+			return fmt.Errorf("failed to set inner map of map %s: %w", b.name, err)
+		}
+		if err = checkInnerMap(template, info); err != nil {
+			return fmt.Errorf("failed to set inner map of map %s: map %s has %v: %w", b.name, inner.name, err, syscall.EINVAL)
+		}
+	}
+
+	fd := uint32(inner.GetFd())
+	return b.Update(key, unsafe.Pointer(&fd))
+}
+
+// GetInnerMapID returns the ID of the inner map stored at key. From
+// userspace, maps of maps are updated with fds but hold map IDs.
+func (b *BPFMap) GetInnerMapID(key unsafe.Pointer) (uint32, error) {
+	if !isMapOfMaps(b.Type()) {
+		return 0, fmt.Errorf("failed to get inner map of map %s: %s is not a map of maps: %w", b.name, b.Type(), syscall.EINVAL)
+	}
+
+	value, err := b.GetValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return nativeEndian.Uint32(value), nil
+}
+
+// OpenInnerMap opens the inner map stored at key. Close must be called to
+// release it.
+func (b *BPFMap) OpenInnerMap(key unsafe.Pointer) (*BPFMap, error) {
+	id, err := b.GetInnerMapID(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewBPFMapFromID(id)
+}
@@ -0,0 +1,47 @@
+package libbpfgo
+
+import (
+	"testing"
+)
+
+func TestCheckInnerMap(t *testing.T) {
+	template := BPFMapInfo{Type: MapTypeArray, KeySize: 4, ValueSize: 8, MaxEntries: 16}
+
+	tests := []struct {
+		name   string
+		modify func(info *BPFMapInfo)
+		ok     bool
+	}{
+		{"same", func(info *BPFMapInfo) {}, true},
+		{"type", func(info *BPFMapInfo) { info.Type = MapTypeHash }, false},
+		{"key size", func(info *BPFMapInfo) { info.KeySize = 8 }, false},
+		{"value size", func(info *BPFMapInfo) { info.ValueSize = 4 }, false},
+		{"flags", func(info *BPFMapInfo) { info.MapFlags = 1 }, false},
+		{"max entries", func(info *BPFMapInfo) { info.MaxEntries = 32 }, false},
+		{"name", func(info *BPFMapInfo) { info.Name = "other" }, true},
+	}
+
+	for _, tt := range tests {
+		inner := template
+		tt.modify(&inner)
+		if err := checkInnerMap(&template, &inner); (err == nil) != tt.ok {
+			t.Errorf("%s: unexpected result %v", tt.name, err)
+		}
+	}
+
+	// max entries may differ for hash maps and BPF_F_INNER_MAP arrays
+	hash := BPFMapInfo{Type: MapTypeHash, KeySize: 4, ValueSize: 8, MaxEntries: 16}
+	inner := hash
+	inner.MaxEntries = 32
+	if err := checkInnerMap(&hash, &inner); err != nil {
+		t.Errorf("hash: unexpected error %v", err)
+	}
+
+	flexible := template
+	flexible.MapFlags = bpfFInnerMap
+	inner = flexible
+	inner.MaxEntries = 32
+	if err := checkInnerMap(&flexible, &inner); err != nil {
+		t.Errorf("BPF_F_INNER_MAP: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// MapFlagsMmapable is the BPF_F_MMAPABLE map flag, for
+	// BPFMapCreateOpts.MapFlags: the array can be mapped with Mmap
+	MapFlagsMmapable uint32 = C.BPF_F_MMAPABLE
+
+	bpfFRdonly = C.BPF_F_RDONLY
+)
+
+// Mmap maps the values of an array map created with BPF_F_MMAPABLE into
+// memory, so that they are read and written without syscalls. Value i
+// starts at i * ValueSize() rounded up to 8 bytes. The mapping is read-only
+// if the map is frozen or read-only from userspace. It is valid until
+// Unmap, Close for maps not part of a module, or Module.Close.
+func (b *BPFMap) Mmap() ([]byte, error) {
+	if b.mmaped != nil {
+		return b.mmaped, nil
+	}
+
+	info, err := b.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to mmap map %s: %w", b.name, err)
+	}
+	if info.Type != MapTypeArray || info.MapFlags&MapFlagsMmapable == 0 {
+		return nil, fmt.Errorf("failed to mmap map %s: not an array created with BPF_F_MMAPABLE: %w", b.name, syscall.EINVAL)
+	}
+
+	prot := syscall.PROT_READ | syscall.PROT_WRITE
+	if info.Frozen || info.MapFlags&bpfFRdonly != 0 {
+		prot = syscall.PROT_READ
+	}
+
+	size := int(info.MaxEntries) * ((int(info.ValueSize) + 7) &^ 7)
+	pageSize := os.Getpagesize()
+	mapped, err := syscall.Mmap(int(b.fd), 0, (size+pageSize-1)/pageSize*pageSize, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mmap map %s: %w", b.name, err)
+	}
+
+	b.mmaped = mapped[:size]
+	if b.module != nil {
+		b.module.mmaped = append(b.module.mmaped, b)
+	}
+	return b.mmaped, nil
+}
+
+// MmapSlice maps the values of an array map created with BPF_F_MMAPABLE as
+// a slice of T (see Mmap). The size of T must be the size of the values
+// rounded up to 8 bytes, as the kernel lays them out.
+func MmapSlice[T any](b *BPFMap) ([]T, error) {
+	var value T
+	stride := (b.ValueSize() + 7) &^ 7
+	if size := int(unsafe.Sizeof(value)); size != stride {
+		return nil, fmt.Errorf("failed to mmap map %s: type %T has size %d, values take %d bytes", b.name, value, size, stride)
+	}
+
+	mapped, err := b.Mmap()
+	if err != nil {
+		return nil, err
+	}
+	if len(mapped) == 0 {
+		return nil, nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&mapped[0])), len(mapped)/stride), nil
+}
+
+// Unmap releases the memory returned by Mmap, which must not be used
+// anymore
+func (b *BPFMap) Unmap() error {
+	if b.mmaped == nil {
+		return nil
+	}
+
+	// unmap the whole pages
+	if err := syscall.Munmap(b.mmaped[:cap(b.mmaped)]); err != nil {
+		return fmt.Errorf("failed to unmap map %s: %w", b.name, err)
+	}
+	b.mmaped = nil
+
+	if b.module != nil {
+		for i, mapped := range b.module.mmaped {
+			if mapped == b {
+				b.module.mmaped = append(b.module.mmaped[:i], b.module.mmaped[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Freeze makes the map read-only from userspace: updates and writable
+// mmaps fail with EPERM, while BPF programs can still update it. It fails
+// with EBUSY while the map is mapped writable (see Mmap).
+func (b *BPFMap) Freeze() error {
+	errC := C.bpf_map_freeze(b.fd)
+	if errC != 0 {
+		return fmt.Errorf("failed to freeze map %s: %w", b.name, syscall.Errno(-errC))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package libbpfgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMmapSliceSize(t *testing.T) {
+	// values of 4 bytes take 8 bytes in mmaped arrays
+	bpfMap := &BPFMap{name: "test", fd: -1, info: &BPFMapInfo{Type: MapTypeArray, ValueSize: 4}}
+
+	_, err := MmapSlice[uint32](bpfMap)
+	if err == nil || !strings.Contains(err.Error(), "has size 4, values take 8 bytes") {
+		t.Errorf("expected a size mismatch, got %v", err)
+	}
+
+	type padded struct {
+		Value uint32
+		_     uint32
+	}
+	// the size matches, mmaping fails on the invalid fd
+	_, err = MmapSlice[padded](bpfMap)
+	if err == nil || strings.Contains(err.Error(), "has size") {
+		t.Errorf("expected a mmap failure, got %v", err)
+	}
+}
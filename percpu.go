@@ -0,0 +1,240 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/aquasecurity/libbpfgo/helpers"
+)
+
+func isPerCPU(mapType MapType) bool {
+	switch mapType {
+	case MapTypePerCPUHash, MapTypePerCPUArray, MapTypeLRUPerCPUHash, MapTypePerCPUCgroupStorage:
+		return true
+	}
+	return false
+}
+
+// perCPUValueSize returns the size of the value of one CPU, as the kernel
+// lays values out: rounded up to 8 bytes
+func (b *BPFMap) perCPUValueSize() int {
+	return (b.ValueSize() + 7) &^ 7
+}
+
+// perCPUSize returns the size of the value of all CPUs
+func (b *BPFMap) perCPUSize() (int, error) {
+	ncpus, err := helpers.NumPossibleCPUs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get number of possible CPUs: %w", err)
+	}
+	return ncpus * b.perCPUValueSize(), nil
+}
+
+func (b *BPFMap) checkPerCPU() error {
+	if !isPerCPU(b.Type()) {
+		return fmt.Errorf("map %s is not a per-CPU map: %s: %w", b.name, b.Type(), syscall.EINVAL)
+	}
+	return nil
+}
+
+// splitPerCPU splits the value of all CPUs into one value per CPU, without
+// the padding of each value
+func (b *BPFMap) splitPerCPU(raw []byte) [][]byte {
+	stride := b.perCPUValueSize()
+	values := make([][]byte, 0, len(raw)/stride)
+	for i := 0; i+stride <= len(raw); i += stride {
+		values = append(values, raw[i:i+b.ValueSize()])
+	}
+	return values
+}
+
+// joinPerCPU lays the values of all CPUs out as the kernel expects them
+func (b *BPFMap) joinPerCPU(values [][]byte) ([]byte, error) {
+	ncpus, err := helpers.NumPossibleCPUs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of possible CPUs: %w", err)
+	}
+	if len(values) != ncpus {
+		return nil, fmt.Errorf("%d values for %d possible CPUs: %w", len(values), ncpus, syscall.EINVAL)
+	}
+
+	stride := b.perCPUValueSize()
+	raw := make([]byte, ncpus*stride)
+	for cpu, value := range values {
+		if len(value) != b.ValueSize() {
+			return nil, fmt.Errorf("value of CPU %d has size %d, expected %d: %w", cpu, len(value), b.ValueSize(), syscall.EINVAL)
+		}
+		copy(raw[cpu*stride:], value)
+	}
+	return raw, nil
+}
+
+// GetValuePerCPU looks the value of key up in a per-CPU map. It returns
+// one value per possible CPU, indexed by CPU number.
+func (b *BPFMap) GetValuePerCPU(key unsafe.Pointer) ([][]byte, error) {
+	if err := b.checkPerCPU(); err != nil {
+		return nil, err
+	}
+	size, err := b.perCPUSize()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, size)
+	errC := C.bpf_map_lookup_elem(b.fd, key, unsafe.Pointer(&raw[0]))
+	if errC != 0 {
+		return nil, fmt.Errorf("failed to lookup value %v in map %s: %w", key, b.name, syscall.Errno(-errC))
+	}
+	return b.splitPerCPU(raw), nil
+}
+
+// UpdatePerCPU updates key in a per-CPU map with one value per possible
+// CPU, indexed by CPU number
+func (b *BPFMap) UpdatePerCPU(key unsafe.Pointer, values [][]byte) error {
+	if err := b.checkPerCPU(); err != nil {
+		return err
+	}
+	raw, err := b.joinPerCPU(values)
+	if err != nil {
+		return fmt.Errorf("failed to update map %s: %w", b.name, err)
+	}
+	return b.Update(key, unsafe.Pointer(&raw[0]))
+}
+
+// GetValueBatchPerCPU is GetValueBatch for per-CPU maps: each value is
+// split into one value per possible CPU.
+func (b *BPFMap) GetValueBatchPerCPU(keys, startKey, nextKey unsafe.Pointer, count uint32) ([][][]byte, error) {
+	if err := b.checkPerCPU(); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	size, err := b.perCPUSize()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		values = make([]byte, size*int(count))
+		countC = C.uint(count)
+	)
+
+	opts := &BPFMapBatchOpts{
+		Sz:        uint64(unsafe.Sizeof(BPFMapBatchOpts{})),
+		ElemFlags: C.BPF_ANY,
+		Flags:     C.BPF_ANY,
+	}
+
+	errC := C.bpf_map_lookup_batch(b.fd, startKey, nextKey, keys, unsafe.Pointer(&values[0]), &countC, bpfMapBatchOptsToC(opts))
+	if errC != 0 {
+		sc := syscall.Errno(-errC)
+		if sc != syscall.EFAULT && uint32(countC) != count {
+			return b.collectBatchValuesPerCPU(values, uint32(countC), size),
+				fmt.Errorf("failed to retrieve ALL elements in map %s, fetched (%d/%d): %w", b.name, uint32(countC), count, sc)
+		}
+		return nil, fmt.Errorf("failed to batch lookup values %v in map %s: %w", keys, b.name, sc)
+	}
+
+	return b.collectBatchValuesPerCPU(values, count, size), nil
+}
+
+func (b *BPFMap) collectBatchValuesPerCPU(values []byte, count uint32, size int) [][][]byte {
+	var collected [][][]byte
+	for i := 0; i < int(count); i++ {
+		collected = append(collected, b.splitPerCPU(values[i*size:(i+1)*size]))
+	}
+	return collected
+}
+
+// UpdateBatchPerCPU is UpdateBatch for per-CPU maps: values holds, for
+// each of the count keys, one value per possible CPU.
+func (b *BPFMap) UpdateBatchPerCPU(keys unsafe.Pointer, values [][][]byte, count uint32) error {
+	if err := b.checkPerCPU(); err != nil {
+		return err
+	}
+	if len(values) != int(count) {
+		return fmt.Errorf("failed to batch update map %s: %d values for %d keys: %w", b.name, len(values), count, syscall.EINVAL)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	var raw []byte
+	for _, perCPU := range values {
+		value, err := b.joinPerCPU(perCPU)
+		if err != nil {
+			return fmt.Errorf("failed to batch update map %s: %w", b.name, err)
+		}
+		raw = append(raw, value...)
+	}
+	return b.UpdateBatch(keys, unsafe.Pointer(&raw[0]), count)
+}
+
+// perCPUCounters decodes per-CPU values holding 32 or 64-bit counters
+func perCPUCounters(values [][]byte) ([]uint64, error) {
+	counters := make([]uint64, 0, len(values))
+	for cpu, value := range values {
+		switch len(value) {
+		case 4:
+			counters = append(counters, uint64(nativeEndian.Uint32(value)))
+		case 8:
+			counters = append(counters, nativeEndian.Uint64(value))
+		default:
+			return nil, fmt.Errorf("value of CPU %d has size %d, not a 32 or 64-bit counter", cpu, len(value))
+		}
+	}
+	if len(counters) == 0 {
+		return nil, fmt.Errorf("no value")
+	}
+	return counters, nil
+}
+
+// SumPerCPU sums per-CPU counters, as returned by GetValuePerCPU
+func SumPerCPU(values [][]byte) (uint64, error) {
+	counters, err := perCPUCounters(values)
+	if err != nil {
+		return 0, err
+	}
+	var sum uint64
+	for _, c := range counters {
+		sum += c
+	}
+	return sum, nil
+}
+
+// MinPerCPU returns the smallest of per-CPU counters
+func MinPerCPU(values [][]byte) (uint64, error) {
+	counters, err := perCPUCounters(values)
+	if err != nil {
+		return 0, err
+	}
+	min := counters[0]
+	for _, c := range counters[1:] {
+		if c < min {
+			min = c
+		}
+	}
+	return min, nil
+}
+
+// MaxPerCPU returns the largest of per-CPU counters
+func MaxPerCPU(values [][]byte) (uint64, error) {
+	counters, err := perCPUCounters(values)
+	if err != nil {
+		return 0, err
+	}
+	max := counters[0]
+	for _, c := range counters[1:] {
+		if c > max {
+			max = c
+		}
+	}
+	return max, nil
+}
@@ -0,0 +1,101 @@
+package libbpfgo
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/libbpfgo/helpers"
+)
+
+func TestPerCPUAggregation(t *testing.T) {
+	u64 := func(v uint64) []byte {
+		b := make([]byte, 8)
+		nativeEndian.PutUint64(b, v)
+		return b
+	}
+	values := [][]byte{u64(3), u64(10), u64(1), u64(6)}
+
+	sum, err := SumPerCPU(values)
+	if err != nil || sum != 20 {
+		t.Errorf("expected a sum of 20, got %d (%v)", sum, err)
+	}
+	min, err := MinPerCPU(values)
+	if err != nil || min != 1 {
+		t.Errorf("expected a min of 1, got %d (%v)", min, err)
+	}
+	max, err := MaxPerCPU(values)
+	if err != nil || max != 10 {
+		t.Errorf("expected a max of 10, got %d (%v)", max, err)
+	}
+
+	if _, err = SumPerCPU([][]byte{{1, 2}}); err == nil {
+		t.Error("expected an error for 16-bit values")
+	}
+	if _, err = MaxPerCPU(nil); err == nil {
+		t.Error("expected an error without values")
+	}
+}
+
+func TestPerCPULayout(t *testing.T) {
+	ncpus, err := helpers.NumPossibleCPUs()
+	if err != nil {
+		t.Skipf("no possible CPUs: %v", err)
+	}
+
+	// 4-byte values are padded to 8 bytes for each CPU
+	b := &BPFMap{name: "percpu", info: &BPFMapInfo{Type: MapTypePerCPUArray, KeySize: 4, ValueSize: 4}}
+
+	values := make([][]byte, ncpus)
+	for cpu := range values {
+		values[cpu] = []byte{byte(cpu), 0, 0, 1}
+	}
+	raw, err := b.joinPerCPU(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != ncpus*8 {
+		t.Fatalf("expected %d bytes, got %d", ncpus*8, len(raw))
+	}
+
+	split := b.splitPerCPU(raw)
+	if len(split) != ncpus {
+		t.Fatalf("expected %d values, got %d", ncpus, len(split))
+	}
+	for cpu, value := range split {
+		if len(value) != 4 || value[0] != byte(cpu) || value[3] != 1 {
+			t.Errorf("unexpected value %v for CPU %d", value, cpu)
+		}
+	}
+
+	if _, err = b.joinPerCPU(values[1:]); err == nil {
+		t.Error("expected an error for missing CPUs")
+	}
+}
+
+func TestCollectBatchValuesStride(t *testing.T) {
+	// 2 per-CPU values of 4 bytes on 2 CPUs, each CPU taking 8 bytes
+	values := []byte{
+		1, 1, 1, 1, 0, 0, 0, 0, 2, 2, 2, 2, 0, 0, 0, 0,
+		3, 3, 3, 3, 0, 0, 0, 0, 4, 4, 4, 4, 0, 0, 0, 0,
+	}
+	collected := collectBatchValues(values, 2, 16, 4)
+	if len(collected) != 2 {
+		t.Fatalf("expected 2 values, got %d", len(collected))
+	}
+	for i, expected := range []byte{1, 3} {
+		if len(collected[i]) != 4 || collected[i][0] != expected || collected[i][3] != expected {
+			t.Errorf("value %d: expected 4 bytes of %d, got %v", i, expected, collected[i])
+		}
+	}
+}
+
+func TestPerCPUEmptyBatch(t *testing.T) {
+	bpfMap := &BPFMap{name: "percpu", fd: -1, info: &BPFMapInfo{Type: MapTypePerCPUHash, KeySize: 4, ValueSize: 8}}
+
+	values, err := bpfMap.GetValueBatchPerCPU(nil, nil, nil, 0)
+	if err != nil || values != nil {
+		t.Errorf("GetValueBatchPerCPU: unexpected %v, %v", values, err)
+	}
+	if err = bpfMap.UpdateBatchPerCPU(nil, nil, 0); err != nil {
+		t.Errorf("UpdateBatchPerCPU: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package libbpfgo
+
+/*
+#include <stdlib.h>
+#include <unistd.h>
+
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenPinnedMap opens the map pinned at path. As maps opened by ID, the
+// map is not part of any module and Close must be called to release it.
+func OpenPinnedMap(path string) (*BPFMap, error) {
+	fd, err := objGet(path, "bpf-map")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pinned map %s: %w", path, err)
+	}
+	return newBPFMapFromFD(fd)
+}
+
+// OpenPinnedProg opens the program pinned at path. Close must be called to
+// release it.
+func OpenPinnedProg(path string) (*BPFProg, error) {
+	fd, err := objGet(path, "bpf-prog")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pinned program %s: %w", path, err)
+	}
+	prog, err := newBPFProgFromFD(fd)
+	if err != nil {
+		return nil, err
+	}
+	prog.pinnedPath = path
+	return prog, nil
+}
+
+// OpenPinnedLink opens the link pinned at path. Close must be called to
+// release it, which does not detach it.
+func OpenPinnedLink(path string) (*BPFLink, error) {
+	fd, err := objGet(path, "bpf_link")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pinned link %s: %w", path, err)
+	}
+	return newBPFLinkFromFD(fd)
+}
+
+// objGet opens the object pinned at path, which must be of kind, as named
+// by the kernel for its anonymous inodes
+func objGet(path, kind string) (C.int, error) {
+	cs := C.CString(path)
+	fd := C.bpf_obj_get(cs)
+	C.free(unsafe.Pointer(cs))
+	if fd < 0 {
+		return -1, syscall.Errno(-fd)
+	}
+
+	target, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		C.close(fd)
+		return -1, err
+	}
+	if target != "anon_inode:"+kind {
+		C.close(fd)
+		return -1, fmt.Errorf("%s is not a %s: %w", target, kind, syscall.EINVAL)
+	}
+	return fd, nil
+}
+
+func objPin(fd C.int, path string) error {
+	cs := C.CString(path)
+	errC := C.bpf_obj_pin(fd, cs)
+	C.free(unsafe.Pointer(cs))
+	if errC < 0 {
+		return syscall.Errno(-errC)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	// ErrMapEmpty is returned by Pop and Peek on an empty queue or stack
+	ErrMapEmpty = errors.New("map is empty")
+	// ErrMapFull is returned by Push on a full queue or stack, unless
+	// pushed with MapFlagUpdateExist
+	ErrMapFull = errors.New("map is full")
+)
+
+func (b *BPFMap) checkQueueOrStack() error {
+	if t := b.Type(); t != MapTypeQueue && t != MapTypeStack {
+		return fmt.Errorf("map %s is not a queue or a stack: %s: %w", b.name, t, syscall.EINVAL)
+	}
+	return nil
+}
+
+// Push adds value to a queue or a stack. With MapFlagUpdateExist, pushing
+// to a full map drops its oldest value, otherwise it fails with ErrMapFull.
+func (b *BPFMap) Push(value unsafe.Pointer, flags MapFlag) error {
+	if err := b.checkQueueOrStack(); err != nil {
+		return err
+	}
+
+	errC := C.bpf_map_update_elem(b.fd, nil, value, C.ulonglong(flags))
+	if errC != 0 {
+		errno := syscall.Errno(-errC)
+		if errno == syscall.E2BIG {
+			return fmt.Errorf("failed to push to map %s: %w", b.name, ErrMapFull)
+		}
+		return fmt.Errorf("failed to push to map %s: %w", b.name, errno)
+	}
+	return nil
+}
+
+// Pop removes the value at the head of a queue or at the top of a stack,
+// and returns it
+func (b *BPFMap) Pop() ([]byte, error) {
+	if err := b.checkQueueOrStack(); err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, b.ValueSize())
+	errC := C.bpf_map_lookup_and_delete_elem(b.fd, nil, unsafe.Pointer(&value[0]))
+	if errC != 0 {
+		errno := syscall.Errno(-errC)
+		if errno == syscall.ENOENT {
+			return nil, fmt.Errorf("failed to pop from map %s: %w", b.name, ErrMapEmpty)
+		}
+		return nil, fmt.Errorf("failed to pop from map %s: %w", b.name, errno)
+	}
+	return value, nil
+}
+
+// Peek returns the value Pop would return, without removing it
+func (b *BPFMap) Peek() ([]byte, error) {
+	if err := b.checkQueueOrStack(); err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, b.ValueSize())
+	errC := C.bpf_map_lookup_elem(b.fd, nil, unsafe.Pointer(&value[0]))
+	if errC != 0 {
+		errno := syscall.Errno(-errC)
+		if errno == syscall.ENOENT {
+			return nil, fmt.Errorf("failed to peek into map %s: %w", b.name, ErrMapEmpty)
+		}
+		return nil, fmt.Errorf("failed to peek into map %s: %w", b.name, errno)
+	}
+	return value, nil
+}
@@ -0,0 +1,21 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_BLOOM_FILTER);
+    __type(value, u32);
+    __uint(max_entries, 1024);
+} blocklist SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 value = bpf_get_current_pid_tgid() >> 32;
+
+    if (bpf_map_peek_elem(&blocklist, &value) == 0)
+        bpf_printk("blocked pid %d", value);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
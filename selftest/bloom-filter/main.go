@@ -0,0 +1,72 @@
+package main
+
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	blocklist, err := bpfModule.GetMap("blocklist")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = blocklist.SetMapExtra(3); err != nil {
+		exitWithErr(err)
+	}
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	info, err := blocklist.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if info.MapExtra != 3 || blocklist.MapExtra() != 3 {
+		exitWithErr(fmt.Errorf("map extra %d, expected 3", info.MapExtra))
+	}
+	if err = blocklist.SetMapExtra(4); err == nil {
+		exitWithErr(fmt.Errorf("map extra set after load"))
+	}
+
+	for i := uint32(0); i < 100; i++ {
+		value := i * 2
+		if err = blocklist.BloomAdd(unsafe.Pointer(&value)); err != nil {
+			exitWithErr(err)
+		}
+	}
+
+	// no false negatives, few false positives
+	positives := 0
+	for i := uint32(0); i < 200; i++ {
+		found, err := blocklist.BloomContains(unsafe.Pointer(&i))
+		if err != nil {
+			exitWithErr(err)
+		}
+		if i%2 == 0 && !found {
+			exitWithErr(fmt.Errorf("%d added but not found", i))
+		}
+		if found {
+			positives++
+		}
+	}
+	if positives > 150 {
+		exitWithErr(fmt.Errorf("%d of 200 values found, 100 added", positives))
+	}
+}
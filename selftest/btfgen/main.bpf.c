@@ -0,0 +1,32 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_RINGBUF);
+    __uint(max_entries, 1 << 24);
+} events SEC(".maps");
+
+struct event {
+    int pid;
+    int ppid;
+};
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    struct task_struct *task = (struct task_struct *) bpf_get_current_task();
+
+    struct event *e = bpf_ringbuf_reserve(&events, sizeof(struct event), 0);
+    if (!e) {
+        return 1;
+    }
+    e->pid = BPF_CORE_READ(task, tgid);
+    e->ppid = BPF_CORE_READ(task, real_parent, tgid);
+    bpf_ringbuf_submit(e, 0);
+
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
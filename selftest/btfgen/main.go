@@ -0,0 +1,93 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	tmpDir, err := os.MkdirTemp("", "btfgen")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	btfPath := filepath.Join(tmpDir, "min.btf")
+	if err = bpf.GenerateMinimalBTF("/sys/kernel/btf/vmlinux", btfPath, "main.bpf.o"); err != nil {
+		exitWithErr(err)
+	}
+
+	info, err := os.Stat(btfPath)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if info.Size() > 64*1024 {
+		exitWithErr(fmt.Errorf("minimized BTF is too big: %d bytes", info.Size()))
+	}
+
+	bpfModule, err := bpf.NewModuleFromFileArgs(bpf.NewModuleArgs{
+		BPFObjPath: "main.bpf.o",
+		BTFObjPath: btfPath,
+	})
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if _, err = prog.AttachKprobe("__x64_sys_mmap"); err != nil {
+		exitWithErr(err)
+	}
+
+	eventsChannel := make(chan []byte)
+	rb, err := bpfModule.InitRingBuf("events", eventsChannel)
+	if err != nil {
+		exitWithErr(err)
+	}
+	rb.Start()
+	defer rb.Stop()
+
+	go func() {
+		for {
+			syscall.Mmap(999, 999, 999, 1, 1)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case b := <-eventsChannel:
+			if int(binary.LittleEndian.Uint32(b)) != os.Getpid() {
+				continue
+			}
+			// the relocated read of task->real_parent->tgid must see our parent
+			if ppid := int(binary.LittleEndian.Uint32(b[4:])); ppid != os.Getppid() {
+				exitWithErr(fmt.Errorf("wrong parent pid: expected %d, got %d", os.Getppid(), ppid))
+			}
+			return
+		case <-timeout:
+			exitWithErr(fmt.Errorf("no event with the expected parent pid"))
+		}
+	}
+}
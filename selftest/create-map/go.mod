@@ -5,4 +5,7 @@ go 1.18
 replace github.com/aquasecurity/libbpfgo => ../../
 
 require github.com/aquasecurity/libbpfgo v0.2.1-libbpf-0.4.0
-require golang.org/x/sys v0.0.0-20210514084401-e8d321eab015 // indirect
+require (
+	github.com/ulikunitz/xz v0.5.10 // indirect
+	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015 // indirect
+)
@@ -3,6 +3,7 @@ package main
 import "C"
 
 import (
+	"encoding/binary"
 	"fmt"
 	"log"
 	"os"
@@ -22,8 +23,8 @@ import (
 	use them as a normal map there as those operations only require
 	a file descriptor.
 
-	You can update values in the map from userspace, but currently
-	can't retrieve values outright.
+	The definition of the map is read back from the kernel, so it can
+	be used from userspace like any other map, then closed.
 
 	For example:
 	https://elixir.bootlin.com/linux/latest/source/samples/bpf/fds_example.c
@@ -56,4 +57,40 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if m.Name() != "foobar" || m.Type() != libbpfgo.MapTypeHash ||
+		m.KeySize() != 4 || m.ValueSize() != 4 || m.GetMaxEntries() != 420 {
+		log.Fatalf("unexpected map %s of type %s", m.Name(), m.Type())
+	}
+
+	value, err := m.GetValue(key1Unsafe)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if binary.LittleEndian.Uint32(value) != value1 {
+		log.Fatalf("got value %v, expected %d", value, value1)
+	}
+
+	keys := []uint32{2, 3}
+	values := []uint32{56, 57}
+	err = m.UpdateBatch(unsafe.Pointer(&keys[0]), unsafe.Pointer(&values[0]), uint32(len(keys)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	count := 0
+	it := m.Iterator()
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil {
+		log.Fatal(it.Err())
+	}
+	if count != 3 {
+		log.Fatalf("iterated over %d keys, expected 3", count)
+	}
+
+	if err = m.Close(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,28 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct config {
+    u32 pid;
+    u32 step;
+};
+
+const volatile struct config cfg = {};
+const volatile u32 unused_flag = 0;
+
+u64 mmap_calls = 0; // .bss
+u64 last_step = 1;  // .data
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    if ((bpf_get_current_pid_tgid() >> 32) != cfg.pid)
+        return 0;
+
+    __sync_fetch_and_add(&mmap_calls, cfg.step);
+    last_step = cfg.step;
+
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
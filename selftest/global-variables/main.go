@@ -0,0 +1,117 @@
+package main
+
+import "C"
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"fmt"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+type config struct {
+	Pid  uint32
+	Step uint32
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	// the object BTF describes the variable types
+	spec, err := bpfModule.BTF()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if !spec.FieldExists("config", "step") {
+		exitWithErr(fmt.Errorf("struct config has no step member in the object BTF"))
+	}
+
+	err = bpfModule.InitGlobalVariable("cfg", config{Pid: uint32(os.Getpid()), Step: 2})
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	// the value has to match the BTF type of the variable
+	if err = bpfModule.InitGlobalVariable("unused_flag", uint64(1)); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, wrong size for global variable"))
+	}
+	if err = bpfModule.InitGlobalVariable("cfg", uint64(1)); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, wrong kind for global variable"))
+	}
+	if _, err = bpfModule.GetGlobalVariable("NewYorkYankeesRule"); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, non-existent global variable"))
+	}
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	// .rodata is frozen once loaded
+	if err = bpfModule.InitGlobalVariable("cfg", config{}); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, .rodata written after load"))
+	}
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if _, err = prog.AttachKprobe("__x64_sys_mmap"); err != nil {
+		exitWithErr(err)
+	}
+
+	calls, err := bpfModule.GetGlobalVariable("mmap_calls")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if calls.Section() != ".bss" {
+		exitWithErr(fmt.Errorf("wrong section %s for mmap_calls", calls.Section()))
+	}
+	// live view of the kernel memory
+	view := calls.Bytes()
+
+	syscall.Mmap(999, 999, 999, 1, 1)
+	syscall.Mmap(999, 999, 999, 1, 1)
+	time.Sleep(100 * time.Millisecond)
+
+	var count uint64
+	if err = calls.Read(&count); err != nil {
+		exitWithErr(err)
+	}
+	if count < 4 {
+		exitWithErr(fmt.Errorf("unexpected mmap_calls value %d", count))
+	}
+	if view[0] == 0 {
+		exitWithErr(fmt.Errorf("memory-mapped view of .bss not updated"))
+	}
+
+	// writes to .data are seen by the kernel
+	lastStep, err := bpfModule.GetGlobalVariable("last_step")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = lastStep.Set(uint64(0)); err != nil {
+		exitWithErr(err)
+	}
+	syscall.Mmap(999, 999, 999, 1, 1)
+	time.Sleep(100 * time.Millisecond)
+
+	var step uint64
+	if err = lastStep.Read(&step); err != nil {
+		exitWithErr(err)
+	}
+	if step != 2 {
+		exitWithErr(fmt.Errorf("unexpected last_step value %d", step))
+	}
+}
@@ -0,0 +1,43 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct ipv4_key {
+    u32 prefixlen;
+    u8 addr[4];
+};
+
+struct ipv6_key {
+    u32 prefixlen;
+    u8 addr[16];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __type(key, struct ipv4_key);
+    __type(value, u32);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+    __uint(max_entries, 64);
+} ipv4 SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __type(key, struct ipv6_key);
+    __type(value, u32);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+    __uint(max_entries, 64);
+} ipv6 SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    struct ipv4_key key = {.prefixlen = 32, .addr = {10, 0, 0, 1}};
+    u32 *v;
+
+    v = bpf_map_lookup_elem(&ipv4, &key);
+    if (v)
+        __sync_fetch_and_add(v, 1);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
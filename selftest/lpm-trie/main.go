@@ -0,0 +1,117 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func getTrie(m *bpf.Module, name string) *bpf.LPMTrie {
+	bpfMap, err := m.GetMap(name)
+	if err != nil {
+		exitWithErr(err)
+	}
+	trie, err := bpf.NewLPMTrie(bpfMap)
+	if err != nil {
+		exitWithErr(err)
+	}
+	return trie
+}
+
+func insert(trie *bpf.LPMTrie, prefix string, value uint32) {
+	if err := trie.Insert(netip.MustParsePrefix(prefix), unsafe.Pointer(&value)); err != nil {
+		exitWithErr(err)
+	}
+}
+
+func expect(value []byte, err error, expected uint32, what string) {
+	if err != nil {
+		exitWithErr(fmt.Errorf("%s: %v", what, err))
+	}
+	if v := binary.LittleEndian.Uint32(value); v != expected {
+		exitWithErr(fmt.Errorf("%s: got %d, expected %d", what, v, expected))
+	}
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	ipv4 := getTrie(bpfModule, "ipv4")
+	insert(ipv4, "10.0.0.0/8", 8)
+	insert(ipv4, "10.1.0.0/16", 16)
+	_, ipnet, _ := net.ParseCIDR("10.1.2.0/24")
+	value := uint32(24)
+	if err = ipv4.InsertIPNet(ipnet, unsafe.Pointer(&value)); err != nil {
+		exitWithErr(err)
+	}
+
+	// longest prefix
+	v, err := ipv4.LongestMatch(netip.MustParseAddr("10.1.2.3"))
+	expect(v, err, 24, "longest match of 10.1.2.3")
+	v, err = ipv4.LongestMatchIP(net.ParseIP("10.1.3.3"))
+	expect(v, err, 16, "longest match of 10.1.3.3")
+	v, err = ipv4.LongestMatch(netip.MustParseAddr("10.2.0.1"))
+	expect(v, err, 8, "longest match of 10.2.0.1")
+	if _, err = ipv4.LongestMatch(netip.MustParseAddr("192.168.0.1")); !errors.Is(err, syscall.ENOENT) {
+		exitWithErr(fmt.Errorf("longest match of 192.168.0.1: %v", err))
+	}
+
+	// exact
+	v, err = ipv4.Lookup(netip.MustParsePrefix("10.1.0.0/16"))
+	expect(v, err, 16, "lookup of 10.1.0.0/16")
+	if _, err = ipv4.Lookup(netip.MustParsePrefix("10.1.2.0/23")); !errors.Is(err, syscall.ENOENT) {
+		exitWithErr(fmt.Errorf("lookup of 10.1.2.0/23, not in the trie: %v", err))
+	}
+	if _, err = ipv4.Lookup(netip.MustParsePrefix("2001:db8::/32")); !errors.Is(err, syscall.EINVAL) {
+		exitWithErr(fmt.Errorf("lookup of an IPv6 prefix in an IPv4 trie: %v", err))
+	}
+
+	if err = ipv4.Delete(netip.MustParsePrefix("10.1.0.0/16")); err != nil {
+		exitWithErr(err)
+	}
+	v, err = ipv4.LongestMatch(netip.MustParseAddr("10.1.3.3"))
+	expect(v, err, 8, "longest match of 10.1.3.3 after delete")
+
+	// iteration yields prefixes back
+	prefixes := make(map[string]uint32)
+	it := ipv4.Iterator()
+	for it.Next() {
+		prefixes[it.Prefix().String()] = binary.LittleEndian.Uint32(it.Value())
+	}
+	if it.Err() != nil {
+		exitWithErr(it.Err())
+	}
+	if len(prefixes) != 2 || prefixes["10.0.0.0/8"] != 8 || prefixes["10.1.2.0/24"] != 24 {
+		exitWithErr(fmt.Errorf("iterated over %v", prefixes))
+	}
+
+	ipv6 := getTrie(bpfModule, "ipv6")
+	insert(ipv6, "2001:db8::/32", 32)
+	insert(ipv6, "2001:db8:1::/48", 48)
+	v, err = ipv6.LongestMatch(netip.MustParseAddr("2001:db8:1::1"))
+	expect(v, err, 48, "longest match of 2001:db8:1::1")
+	v, err = ipv6.LongestMatch(netip.MustParseAddr("2001:db8:2::1"))
+	expect(v, err, 32, "longest match of 2001:db8:2::1")
+}
@@ -0,0 +1,45 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, 4096);
+} learned SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, 4096);
+} learned_copy SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, u32);
+    __uint(max_entries, 4096);
+} other SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, u32);
+    __type(value, u32);
+    __uint(max_entries, 64);
+} percpu SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 key = 0;
+    u64 *v;
+
+    v = bpf_map_lookup_elem(&learned, &key);
+    if (v)
+        __sync_fetch_and_add(v, 1);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
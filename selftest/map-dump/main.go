@@ -0,0 +1,118 @@
+package main
+
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+	"github.com/aquasecurity/libbpfgo/helpers"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func getMap(m *bpf.Module, name string) *bpf.BPFMap {
+	bpfMap, err := m.GetMap(name)
+	if err != nil {
+		exitWithErr(err)
+	}
+	return bpfMap
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	learned := getMap(bpfModule, "learned")
+	const entries = 3000
+	for i := uint32(0); i < entries; i++ {
+		value := uint64(i) * 7
+		if err = learned.Update(unsafe.Pointer(&i), unsafe.Pointer(&value)); err != nil {
+			exitWithErr(err)
+		}
+	}
+
+	var dump bytes.Buffer
+	if err = learned.Dump(&dump); err != nil {
+		exitWithErr(err)
+	}
+
+	// restore into another map of the same definition
+	learnedCopy := getMap(bpfModule, "learned_copy")
+	if err = learnedCopy.Restore(bytes.NewReader(dump.Bytes())); err != nil {
+		exitWithErr(err)
+	}
+	for i := uint32(0); i < entries; i++ {
+		value, err := learnedCopy.GetValue(unsafe.Pointer(&i))
+		if err != nil {
+			exitWithErr(err)
+		}
+		if v := *(*uint64)(unsafe.Pointer(&value[0])); v != uint64(i)*7 {
+			exitWithErr(fmt.Errorf("key %d restored with value %d", i, v))
+		}
+	}
+
+	// incompatible maps are refused
+	if err = getMap(bpfModule, "other").Restore(bytes.NewReader(dump.Bytes())); !errors.Is(err, syscall.EINVAL) {
+		exitWithErr(fmt.Errorf("restore into a map of another type and value size: %v", err))
+	}
+
+	// per-CPU values are preserved
+	percpu := getMap(bpfModule, "percpu")
+	ncpus, err := helpers.NumPossibleCPUs()
+	if err != nil {
+		exitWithErr(err)
+	}
+	key := uint32(1)
+	values := make([][]byte, ncpus)
+	for cpu := range values {
+		values[cpu] = make([]byte, 4)
+		*(*uint32)(unsafe.Pointer(&values[cpu][0])) = uint32(cpu) + 100
+	}
+	if err = percpu.UpdatePerCPU(unsafe.Pointer(&key), values); err != nil {
+		exitWithErr(err)
+	}
+	var percpuDump bytes.Buffer
+	if err = percpu.Dump(&percpuDump); err != nil {
+		exitWithErr(err)
+	}
+	if err = percpu.DeleteKey(unsafe.Pointer(&key)); err != nil {
+		exitWithErr(err)
+	}
+	var rendered bytes.Buffer
+	if err = bpf.DumpToJSON(bytes.NewReader(percpuDump.Bytes()), &rendered); err != nil {
+		exitWithErr(err)
+	}
+	if err = percpu.Restore(&percpuDump); err != nil {
+		exitWithErr(err)
+	}
+	restored, err := percpu.GetValuePerCPU(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	for cpu, value := range restored {
+		if v := *(*uint32)(unsafe.Pointer(&value[0])); v != uint32(cpu)+100 {
+			exitWithErr(fmt.Errorf("CPU %d restored with value %d", cpu, v))
+		}
+	}
+
+	if !json.Valid(rendered.Bytes()) {
+		exitWithErr(fmt.Errorf("invalid JSON rendering: %s", rendered.String()))
+	}
+}
@@ -0,0 +1,39 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+// walked with batches
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, 8192);
+} hash SEC(".maps");
+
+struct lpm_key {
+    u32 prefixlen;
+    u32 addr;
+};
+
+// without batch operations, walked with get_next_key
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __type(key, struct lpm_key);
+    __type(value, u64);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+    __uint(max_entries, 1024);
+} trie SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 key = 0;
+    u64 *v;
+
+    v = bpf_map_lookup_elem(&hash, &key);
+    if (v)
+        __sync_fetch_and_add(v, 1);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,119 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+type lpmKey struct {
+	Prefixlen uint32
+	Addr      uint32
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+// walk iterates over the map, checking each entry is returned once with
+// value key*10, and deletes the entries it is asked to
+func walk(it *bpf.BPFMapEntryIterator, keyOf func([]byte) uint32, del func([]byte) error) (int, error) {
+	seen := make(map[uint32]bool)
+	for it.Next() {
+		key := keyOf(it.Key())
+		if seen[key] {
+			return 0, fmt.Errorf("key %d returned twice", key)
+		}
+		seen[key] = true
+		if value := binary.LittleEndian.Uint64(it.Value()); value != uint64(key)*10 {
+			return 0, fmt.Errorf("key %d has value %d", key, value)
+		}
+		if del != nil {
+			if err := del(it.Key()); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(seen), it.Err()
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	// batches, with chunks smaller than the map
+	hash, err := bpfModule.GetMap("hash")
+	if err != nil {
+		exitWithErr(err)
+	}
+	const hashEntries = 5000
+	for i := uint32(1); i <= hashEntries; i++ {
+		value := uint64(i) * 10
+		if err = hash.Update(unsafe.Pointer(&i), unsafe.Pointer(&value)); err != nil {
+			exitWithErr(err)
+		}
+	}
+
+	hashKey := func(key []byte) uint32 { return binary.LittleEndian.Uint32(key) }
+	it := hash.EntryIterator(64)
+	n, err := walk(it, hashKey, nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if n != hashEntries {
+		exitWithErr(fmt.Errorf("walked %d entries of hash, expected %d", n, hashEntries))
+	}
+
+	// a second walk with the same buffers, deleting every entry
+	it.Reset()
+	n, err = walk(it, hashKey, func(key []byte) error { return hash.DeleteKey(unsafe.Pointer(&key[0])) })
+	if err != nil {
+		exitWithErr(err)
+	}
+	if n != hashEntries {
+		exitWithErr(fmt.Errorf("walked %d entries of hash while deleting, expected %d", n, hashEntries))
+	}
+	it.Reset()
+	if it.Next() {
+		exitWithErr(fmt.Errorf("hash not empty after deleting while walking"))
+	}
+	if it.Err() != nil {
+		exitWithErr(it.Err())
+	}
+
+	// get_next_key fallback
+	trie, err := bpfModule.GetMap("trie")
+	if err != nil {
+		exitWithErr(err)
+	}
+	const trieEntries = 300
+	for i := uint32(1); i <= trieEntries; i++ {
+		key := lpmKey{Prefixlen: 32, Addr: i}
+		value := uint64(i) * 10
+		if err = trie.Update(unsafe.Pointer(&key), unsafe.Pointer(&value)); err != nil {
+			exitWithErr(err)
+		}
+	}
+
+	trieKey := func(key []byte) uint32 { return binary.LittleEndian.Uint32(key[4:]) }
+	n, err = walk(trie.EntryIterator(16), trieKey, func(key []byte) error { return trie.DeleteKey(unsafe.Pointer(&key[0])) })
+	if err != nil {
+		exitWithErr(err)
+	}
+	if n != trieEntries {
+		exitWithErr(fmt.Errorf("walked %d entries of trie while deleting, expected %d", n, trieEntries))
+	}
+}
@@ -0,0 +1,45 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+enum state {
+    STATE_IDLE,
+    STATE_RUNNING,
+};
+
+struct key {
+    u32 pid;
+    char comm[16];
+};
+
+struct value {
+    enum state state;
+    u32 flags : 3;
+    u32 traced : 1;
+    union {
+        u64 count;
+        s64 delta;
+    };
+    u16 ports[2];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, struct key);
+    __type(value, struct value);
+    __uint(max_entries, 16);
+} tasks SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    struct key key = {};
+    struct value *v;
+
+    v = bpf_map_lookup_elem(&tasks, &key);
+    if (v)
+        __sync_fetch_and_add(&v->count, 1);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,88 @@
+package main
+
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+// laid out like struct key and struct value
+type key struct {
+	Pid  uint32
+	Comm [16]byte
+}
+
+type value struct {
+	State uint32
+	Bits  uint32 // flags : 3, traced : 1
+	Count uint64
+	Ports [2]uint16
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	tasks, err := bpfModule.GetMap("tasks")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	k := key{Pid: 1234}
+	copy(k.Comm[:], "bash")
+	v := value{State: 1, Bits: 0b1101, Count: 42, Ports: [2]uint16{80, 443}}
+	if err = tasks.Update(unsafe.Pointer(&k), unsafe.Pointer(&v)); err != nil {
+		exitWithErr(err)
+	}
+
+	keyBytes := unsafe.Slice((*byte)(unsafe.Pointer(&k)), unsafe.Sizeof(k))
+	valueBytes := unsafe.Slice((*byte)(unsafe.Pointer(&v)), unsafe.Sizeof(v))
+	formatted, err := tasks.FormatEntry(keyBytes, valueBytes)
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	var entry interface{}
+	if err = json.Unmarshal([]byte(formatted), &entry); err != nil {
+		exitWithErr(fmt.Errorf("invalid JSON %s: %v", formatted, err))
+	}
+	var expected interface{}
+	json.Unmarshal([]byte(`{
+		"key": {"pid": 1234, "comm": "bash"},
+		"value": {"state": "STATE_RUNNING", "flags": 5, "traced": 1, "count": 42, "delta": 42, "ports": [80, 443]}
+	}`), &expected)
+	if !reflect.DeepEqual(entry, expected) {
+		exitWithErr(fmt.Errorf("unexpected entry %s", formatted))
+	}
+
+	var buf bytes.Buffer
+	encoder, err := bpf.NewMapJSONEncoder(&buf, tasks)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = encoder.EncodeMap(); err != nil {
+		exitWithErr(err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 1 {
+		exitWithErr(fmt.Errorf("encoded %d entries, expected 1: %s", lines, buf.String()))
+	}
+}
@@ -0,0 +1,43 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+// inner map set from userspace with SetInnerMap
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY_OF_MAPS);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+    __uint(max_entries, 4);
+} tenants SEC(".maps");
+
+struct policy {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, 16);
+};
+
+// inner map declared in BTF
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH_OF_MAPS);
+    __type(key, u32);
+    __uint(max_entries, 4);
+    __array(values, struct policy);
+} policies SEC(".maps");
+
+SEC("syscall")
+int lookup_tenant(void *ctx)
+{
+    u32 zero = 0, *value;
+    void *inner;
+
+    inner = bpf_map_lookup_elem(&tenants, &zero);
+    if (!inner)
+        return -1;
+    value = bpf_map_lookup_elem(inner, &zero);
+    if (!value)
+        return -1;
+    return *value;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,124 @@
+package main
+
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	template, err := bpf.CreateMap(bpf.MapTypeArray, "template", 4, 4, 1, nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	tenants, err := bpfModule.GetMap("tenants")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = tenants.SetInnerMap(template); err != nil {
+		exitWithErr(err)
+	}
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+	template.Close()
+
+	// store an inner map, then read it from the program
+	inner, err := bpf.CreateMap(bpf.MapTypeArray, "tenant0", 4, 4, 1, nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer inner.Close()
+
+	key := uint32(0)
+	value := uint32(7)
+	if err = inner.Update(unsafe.Pointer(&key), unsafe.Pointer(&value)); err != nil {
+		exitWithErr(err)
+	}
+	if err = tenants.SetInnerMapByKey(unsafe.Pointer(&key), inner); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("lookup_tenant")
+	if err != nil {
+		exitWithErr(err)
+	}
+	retval, _, err := prog.Run(nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if retval != value {
+		exitWithErr(fmt.Errorf("program read %d, expected %d", retval, value))
+	}
+
+	// read it back
+	innerInfo, err := inner.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	id, err := tenants.GetInnerMapID(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if id != innerInfo.ID {
+		exitWithErr(fmt.Errorf("inner map %d, expected %d", id, innerInfo.ID))
+	}
+
+	opened, err := tenants.OpenInnerMap(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer opened.Close()
+	got, err := opened.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if *(*uint32)(unsafe.Pointer(&got[0])) != value {
+		exitWithErr(fmt.Errorf("inner map has %v, expected %d", got, value))
+	}
+
+	// incompatible inner maps are refused, whether the template was set
+	// from userspace or declared in BTF
+	wrongSize, err := bpf.CreateMap(bpf.MapTypeArray, "wrong_size", 4, 8, 1, nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer wrongSize.Close()
+	if err = tenants.SetInnerMapByKey(unsafe.Pointer(&key), wrongSize); !errors.Is(err, syscall.EINVAL) {
+		exitWithErr(fmt.Errorf("storing an incompatible map: %v", err))
+	}
+
+	policies, err := bpfModule.GetMap("policies")
+	if err != nil {
+		exitWithErr(err)
+	}
+	policy, err := bpf.CreateMap(bpf.MapTypeHash, "policy", 4, 8, 16, nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer policy.Close()
+	if err = policies.SetInnerMapByKey(unsafe.Pointer(&key), policy); err != nil {
+		exitWithErr(err)
+	}
+	if err = policies.SetInnerMapByKey(unsafe.Pointer(&key), inner); !errors.Is(err, syscall.EINVAL) {
+		exitWithErr(fmt.Errorf("storing an incompatible map: %v", err))
+	}
+}
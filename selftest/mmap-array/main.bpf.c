@@ -0,0 +1,30 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct config {
+    u32 enabled;
+    u32 sample_rate;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __type(key, u32);
+    __type(value, struct config);
+    __uint(map_flags, BPF_F_MMAPABLE);
+    __uint(max_entries, 4);
+} config SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 key = 0;
+    struct config *c;
+
+    c = bpf_map_lookup_elem(&config, &key);
+    if (c && c->enabled)
+        bpf_printk("sample rate %d", c->sample_rate);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,110 @@
+package main
+
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+// laid out like struct config, 8 bytes as values in mmaped arrays
+type config struct {
+	Enabled    uint32
+	SampleRate uint32
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	configMap, err := bpfModule.GetMap("config")
+	if err != nil {
+		exitWithErr(err)
+	}
+	configs, err := bpf.MmapSlice[config](configMap)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if len(configs) != 4 {
+		exitWithErr(fmt.Errorf("mapped %d values, expected 4", len(configs)))
+	}
+
+	// written without syscalls, seen by lookups
+	configs[1] = config{Enabled: 1, SampleRate: 100}
+	key := uint32(1)
+	value, err := configMap.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if c := *(*config)(unsafe.Pointer(&value[0])); c != configs[1] {
+		exitWithErr(fmt.Errorf("looked up %+v, expected %+v", c, configs[1]))
+	}
+
+	// and the other way around
+	update := config{Enabled: 1, SampleRate: 10}
+	key = 2
+	if err = configMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&update)); err != nil {
+		exitWithErr(err)
+	}
+	if configs[2] != update {
+		exitWithErr(fmt.Errorf("mapped %+v, expected %+v", configs[2], update))
+	}
+
+	// writable mappings prevent freezing
+	if err = configMap.Freeze(); !errors.Is(err, syscall.EBUSY) {
+		exitWithErr(fmt.Errorf("freeze while mapped writable: %v", err))
+	}
+	if err = configMap.Unmap(); err != nil {
+		exitWithErr(err)
+	}
+	if err = configMap.Freeze(); err != nil {
+		exitWithErr(err)
+	}
+	if err = configMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&update)); !errors.Is(err, syscall.EPERM) {
+		exitWithErr(fmt.Errorf("update of a frozen map: %v", err))
+	}
+
+	// frozen maps are mapped read-only, unmapped by Module.Close
+	mapped, err := configMap.Mmap()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if c := *(*config)(unsafe.Pointer(&mapped[8])); c != (config{Enabled: 1, SampleRate: 100}) {
+		exitWithErr(fmt.Errorf("mapped %+v after freeze", c))
+	}
+
+	// maps created from userspace
+	created, err := bpf.CreateMap(bpf.MapTypeArray, "created", 4, 4, 16, &bpf.BPFMapCreateOpts{
+		Size:     uint64(unsafe.Sizeof(bpf.BPFMapCreateOpts{})),
+		MapFlags: bpf.MapFlagsMmapable,
+	})
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer created.Close()
+	mapped, err = created.Mmap()
+	if err != nil {
+		exitWithErr(err)
+	}
+	// values of 4 bytes take 8
+	if len(mapped) != 16*8 {
+		exitWithErr(fmt.Errorf("mapped %d bytes, expected %d", len(mapped), 16*8))
+	}
+}
@@ -0,0 +1,27 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, 1024);
+} counts SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 pid = bpf_get_current_pid_tgid() >> 32;
+    u64 one = 1, *count;
+
+    count = bpf_map_lookup_elem(&counts, &pid);
+    if (count) {
+        __sync_fetch_and_add(count, 1);
+        return 0;
+    }
+    bpf_map_update_elem(&counts, &pid, &one, BPF_ANY);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,141 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+	"github.com/aquasecurity/libbpfgo/btf"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+// findID walks the IDs of it, looking for id
+func findID(it *bpf.IDIterator, id uint32) bool {
+	for it.Next() {
+		if it.ID() == id {
+			return true
+		}
+	}
+	if it.Err() != nil {
+		exitWithErr(it.Err())
+	}
+	return false
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if _, err = prog.AttachKprobe("__x64_sys_mmap"); err != nil {
+		exitWithErr(err)
+	}
+	counts, err := bpfModule.GetMap("counts")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	progInfo, err := prog.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	mapInfo, err := counts.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	// programs
+	if !findID(bpf.NewProgIDIterator(), progInfo.ID) {
+		exitWithErr(fmt.Errorf("program %d not found", progInfo.ID))
+	}
+	progByID, err := bpf.NewBPFProgFromID(progInfo.ID)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer progByID.Close()
+	if progByID.GetType() != bpf.BPFProgTypeKprobe || progByID.GetName() != "kprobe__sys_mma" {
+		exitWithErr(fmt.Errorf("unexpected program %s of type %s", progByID.GetName(), progByID.GetType()))
+	}
+
+	// maps
+	if !findID(bpf.NewMapIDIterator(), mapInfo.ID) {
+		exitWithErr(fmt.Errorf("map %d not found", mapInfo.ID))
+	}
+	mapByID, err := bpf.NewBPFMapFromID(mapInfo.ID)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer mapByID.Close()
+
+	key := uint32(1)
+	value := uint64(42)
+	if err = counts.Update(unsafe.Pointer(&key), unsafe.Pointer(&value)); err != nil {
+		exitWithErr(err)
+	}
+	got, err := mapByID.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if binary.LittleEndian.Uint64(got) != value {
+		exitWithErr(fmt.Errorf("map %d has value %v, expected %d", mapInfo.ID, got, value))
+	}
+
+	// links
+	linkFound := false
+	it := bpf.NewLinkIDIterator()
+	for it.Next() {
+		l, err := bpf.NewBPFLinkFromID(it.ID())
+		if err != nil {
+			continue // gone in the meantime
+		}
+		info, err := l.Info()
+		l.Close()
+		if err != nil {
+			exitWithErr(err)
+		}
+		if info.ProgID == progInfo.ID && info.Type == bpf.BPFLinkTypePerfEvent {
+			linkFound = true
+		}
+	}
+	if it.Err() != nil {
+		exitWithErr(it.Err())
+	}
+	// kprobes are attached with perf event links (kernel 5.15+)
+	if !linkFound {
+		exitWithErr(fmt.Errorf("no link of program %d found", progInfo.ID))
+	}
+
+	// BTF
+	if !findID(bpf.NewBTFIDIterator(), mapInfo.BTFID) {
+		exitWithErr(fmt.Errorf("BTF %d not found", mapInfo.BTFID))
+	}
+	spec, err := bpf.LoadBTFByID(mapInfo.BTFID)
+	if err != nil {
+		exitWithErr(err)
+	}
+	valueType, err := spec.TypeByID(btf.TypeID(mapInfo.BTFValueTypeID))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if valueType.Name() != "u64" {
+		exitWithErr(fmt.Errorf("map value type is %s, expected u64", valueType.Name()))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	counts, err := bpfModule.GetMap("counts")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	progInfo, err := prog.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	mapInfo, err := counts.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	if progInfo.Type != bpf.BPFProgTypeKprobe {
+		exitWithErr(fmt.Errorf("prog type %s, expected %s", progInfo.Type, bpf.BPFProgTypeKprobe))
+	}
+	if progInfo.ID == 0 || len(progInfo.Tag) != 16 || progInfo.XlatedProgLen == 0 {
+		exitWithErr(fmt.Errorf("unexpected prog info %+v", progInfo))
+	}
+	if since := time.Since(progInfo.LoadTime); since < 0 || since > time.Minute {
+		exitWithErr(fmt.Errorf("prog loaded at %s, %s ago", progInfo.LoadTime, since))
+	}
+	if progInfo.CreatedByUID != uint32(os.Getuid()) {
+		exitWithErr(fmt.Errorf("prog created by uid %d, expected %d", progInfo.CreatedByUID, os.Getuid()))
+	}
+
+	found := false
+	for _, id := range progInfo.MapIDs {
+		if id == mapInfo.ID {
+			found = true
+		}
+	}
+	if !found {
+		exitWithErr(fmt.Errorf("map %d not in prog maps %v", mapInfo.ID, progInfo.MapIDs))
+	}
+
+	if mapInfo.Type != bpf.MapTypeHash || mapInfo.Name != "counts" ||
+		mapInfo.KeySize != 4 || mapInfo.ValueSize != 8 || mapInfo.MaxEntries != 1024 {
+		exitWithErr(fmt.Errorf("unexpected map info %+v", mapInfo))
+	}
+	if mapInfo.BTFID == 0 || mapInfo.BTFKeyTypeID == 0 || mapInfo.BTFValueTypeID == 0 {
+		exitWithErr(fmt.Errorf("map %s has no BTF: %+v", mapInfo.Name, mapInfo))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+const (
+	mapPath  = "/sys/fs/bpf/open_pinned_map"
+	progPath = "/sys/fs/bpf/open_pinned_prog"
+	linkPath = "/sys/fs/bpf/open_pinned_link"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	counts, err := bpfModule.GetMap("counts")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = counts.Pin(mapPath); err != nil {
+		exitWithErr(err)
+	}
+	defer counts.Unpin(mapPath)
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = prog.Pin(progPath); err != nil {
+		exitWithErr(err)
+	}
+	defer prog.Unpin(progPath)
+
+	link, err := prog.AttachKprobe("__x64_sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = link.Pin(linkPath); err != nil {
+		exitWithErr(err)
+	}
+	defer link.Unpin(linkPath)
+
+	// map
+	pinnedMap, err := bpf.OpenPinnedMap(mapPath)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer pinnedMap.Close()
+
+	if pinnedMap.Name() != "counts" || pinnedMap.Type() != bpf.MapTypeHash ||
+		pinnedMap.KeySize() != 4 || pinnedMap.ValueSize() != 8 || pinnedMap.GetMaxEntries() != 1024 {
+		exitWithErr(fmt.Errorf("unexpected map %s of type %s", pinnedMap.Name(), pinnedMap.Type()))
+	}
+
+	key := uint32(1)
+	value := uint64(42)
+	if err = pinnedMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&value)); err != nil {
+		exitWithErr(err)
+	}
+	got, err := counts.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if binary.LittleEndian.Uint64(got) != value {
+		exitWithErr(fmt.Errorf("map has value %v, expected %d", got, value))
+	}
+
+	// program
+	pinnedProg, err := bpf.OpenPinnedProg(progPath)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer pinnedProg.Close()
+
+	progInfo, err := prog.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	pinnedProgInfo, err := pinnedProg.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if pinnedProgInfo.ID != progInfo.ID || pinnedProg.GetType() != bpf.BPFProgTypeKprobe {
+		exitWithErr(fmt.Errorf("unexpected program %d of type %s", pinnedProgInfo.ID, pinnedProg.GetType()))
+	}
+
+	// link
+	pinnedLink, err := bpf.OpenPinnedLink(linkPath)
+	if err != nil {
+		exitWithErr(err)
+	}
+	linkInfo, err := pinnedLink.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if linkInfo.ProgID != progInfo.ID {
+		exitWithErr(fmt.Errorf("link of program %d, expected %d", linkInfo.ProgID, progInfo.ID))
+	}
+	if err = pinnedLink.Close(); err != nil {
+		exitWithErr(err)
+	}
+
+	// wrong kind of object
+	if _, err = bpf.OpenPinnedProg(mapPath); !errors.Is(err, syscall.EINVAL) {
+		exitWithErr(fmt.Errorf("opening a map as a program: %v", err))
+	}
+}
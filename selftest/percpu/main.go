@@ -12,6 +12,7 @@ import (
 	"unsafe"
 
 	bpf "github.com/aquasecurity/libbpfgo"
+	"github.com/aquasecurity/libbpfgo/helpers"
 )
 
 func main() {
@@ -71,4 +72,53 @@ func main() {
 		fmt.Printf("CPU %d: %d\n", i, binary.LittleEndian.Uint32(values[last:last+8]))
 		last += 8
 	}
+
+	// values split by possible CPU
+	ncpus, err := helpers.NumPossibleCPUs()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	perCPU, err := lostEventCounterMap.GetValuePerCPU(unsafe.Pointer(&key))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	if len(perCPU) != ncpus {
+		fmt.Fprintf(os.Stderr, "got %d values for %d possible CPUs\n", len(perCPU), ncpus)
+		os.Exit(-1)
+	}
+	sum, err := bpf.SumPerCPU(perCPU)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	if sum == 0 {
+		fmt.Fprintln(os.Stderr, "no mmap counted")
+		os.Exit(-1)
+	}
+
+	// the program keeps on counting, values can only grow
+	for cpu := range perCPU {
+		binary.LittleEndian.PutUint64(perCPU[cpu], 1000)
+	}
+	err = lostEventCounterMap.UpdatePerCPU(unsafe.Pointer(&key), perCPU)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	perCPU, err = lostEventCounterMap.GetValuePerCPU(unsafe.Pointer(&key))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	min, err := bpf.MinPerCPU(perCPU)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	if min < 1000 {
+		fmt.Fprintf(os.Stderr, "got a value of %d after setting all values to 1000\n", min)
+		os.Exit(-1)
+	}
 }
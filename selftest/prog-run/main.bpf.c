@@ -0,0 +1,30 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct args {
+    int max_entries;
+    int map_fd;
+};
+
+// creates an array map on behalf of userspace
+SEC("syscall")
+int create_map(struct args *ctx)
+{
+    union bpf_attr attr = {};
+    int map_fd;
+
+    attr.map_type = BPF_MAP_TYPE_ARRAY;
+    attr.key_size = 4;
+    attr.value_size = 8;
+    attr.max_entries = ctx->max_entries;
+
+    map_fd = bpf_sys_bpf(BPF_MAP_CREATE, &attr, sizeof(attr));
+    if (map_fd < 0)
+        return 1;
+
+    ctx->map_fd = map_fd;
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,59 @@
+package main
+
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+type args struct {
+	MaxEntries int32
+	MapFd      int32
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("create_map")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	ctx := args{MaxEntries: 16, MapFd: -1}
+	retval, err := prog.RunWithCtx(&ctx)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if retval != 0 {
+		exitWithErr(fmt.Errorf("program failed to create the map: %d", retval))
+	}
+	if ctx.MapFd <= 0 {
+		exitWithErr(fmt.Errorf("program did not return the map fd: %d", ctx.MapFd))
+	}
+	syscall.Close(int(ctx.MapFd))
+
+	// a context smaller than what the program accesses is refused
+	if _, _, err = prog.Run(nil); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, no context"))
+	}
+	if _, err = prog.RunWithCtx(ctx); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, context is not a pointer"))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("kprobe__sys_mmap")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if _, err = prog.AttachKprobe("__x64_sys_mmap"); err != nil {
+		exitWithErr(err)
+	}
+
+	guard, err := bpf.EnableStats(bpf.BPFStatsRunTime)
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer guard.Close()
+
+	statsChan := make(chan []bpf.ProgStats)
+	collector, err := bpfModule.InitStatsCollector(statsChan, 500*time.Millisecond)
+	if err != nil {
+		exitWithErr(err)
+	}
+	collector.Start()
+
+	// trigger the kprobe while the collector samples
+	go func() {
+		for i := 0; i < 100; i++ {
+			syscall.Mmap(-1, 0, 4096, syscall.PROT_READ, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	var runs uint64
+	for i := 0; i < 3; i++ {
+		stats := <-statsChan
+		if len(stats) != 1 || stats[0].Name != "kprobe__sys_mmap" {
+			exitWithErr(fmt.Errorf("unexpected stats %+v", stats))
+		}
+		runs += stats[0].RunCnt
+		if stats[0].RunCnt > 0 && (stats[0].NsPerRun == 0 || stats[0].RunsPerSec == 0) {
+			exitWithErr(fmt.Errorf("unexpected stats %+v", stats[0]))
+		}
+	}
+	collector.Stop()
+
+	if runs == 0 {
+		exitWithErr(fmt.Errorf("no run of %s counted", prog.GetName()))
+	}
+	if _, ok := <-statsChan; ok {
+		exitWithErr(fmt.Errorf("stats channel not closed"))
+	}
+}
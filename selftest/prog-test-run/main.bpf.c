@@ -0,0 +1,22 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+// drops broadcast frames, tags the source MAC address of the others
+SEC("xdp")
+int xdp_tag(struct xdp_md *ctx)
+{
+    void *data_end = (void *)(long) ctx->data_end;
+    struct ethhdr *eth = (void *)(long) ctx->data;
+
+    if ((void *)(eth + 1) > data_end)
+        return XDP_ABORTED;
+
+    if (eth->h_dest[0] == 0xff)
+        return XDP_DROP;
+
+    eth->h_source[0] = 0x42;
+    return XDP_PASS;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,76 @@
+package main
+
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+const (
+	xdpDrop = 1
+	xdpPass = 2
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	prog, err := bpfModule.GetProgram("xdp_tag")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	// ethernet header + some payload
+	packet := make([]byte, 64)
+	copy(packet, []byte{0x02, 0, 0, 0, 0, 1, 0x02, 0, 0, 0, 0, 2, 0x08, 0x00})
+
+	result, err := prog.TestRun(bpf.TestRunOpts{
+		DataIn: packet,
+		Repeat: 100,
+	})
+	if err != nil {
+		exitWithErr(err)
+	}
+	if result.Retval != xdpPass {
+		exitWithErr(fmt.Errorf("expected XDP_PASS, got %d", result.Retval))
+	}
+	expected := append([]byte{}, packet...)
+	expected[6] = 0x42
+	if !bytes.Equal(result.DataOut, expected) {
+		exitWithErr(fmt.Errorf("unexpected packet out: %x", result.DataOut))
+	}
+	if result.Duration <= 0 {
+		exitWithErr(fmt.Errorf("no duration reported"))
+	}
+
+	broadcast := append([]byte{}, packet...)
+	copy(broadcast, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	result, err = prog.TestRun(bpf.TestRunOpts{DataIn: broadcast})
+	if err != nil {
+		exitWithErr(err)
+	}
+	if result.Retval != xdpDrop {
+		exitWithErr(fmt.Errorf("expected XDP_DROP, got %d", result.Retval))
+	}
+
+	// too short for an ethernet header: the kernel refuses it
+	if _, err = prog.TestRun(bpf.TestRunOpts{DataIn: packet[:4]}); err == nil {
+		exitWithErr(fmt.Errorf("undetected error, packet too short"))
+	}
+}
@@ -0,0 +1 @@
+../common/run.sh
\ No newline at end of file
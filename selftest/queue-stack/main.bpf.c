@@ -0,0 +1,26 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_QUEUE);
+    __type(value, u32);
+    __uint(max_entries, 4);
+} queue SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_STACK);
+    __type(value, u32);
+    __uint(max_entries, 4);
+} stack SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 value = 0;
+
+    bpf_map_push_elem(&queue, &value, BPF_EXIST);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,91 @@
+package main
+
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func push(m *bpf.BPFMap, value uint32, flags bpf.MapFlag) error {
+	return m.Push(unsafe.Pointer(&value), flags)
+}
+
+// expect pops or peeks expected from m
+func expect(m *bpf.BPFMap, pop bool, expected uint32) {
+	get := m.Peek
+	if pop {
+		get = m.Pop
+	}
+	value, err := get()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if v := binary.LittleEndian.Uint32(value); v != expected {
+		exitWithErr(fmt.Errorf("got %d from %s, expected %d", v, m.Name(), expected))
+	}
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	queue, err := bpfModule.GetMap("queue")
+	if err != nil {
+		exitWithErr(err)
+	}
+	stack, err := bpfModule.GetMap("stack")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	for _, m := range []*bpf.BPFMap{queue, stack} {
+		if _, err = m.Pop(); !errors.Is(err, bpf.ErrMapEmpty) {
+			exitWithErr(fmt.Errorf("pop from empty %s: %v", m.Name(), err))
+		}
+		if _, err = m.Peek(); !errors.Is(err, bpf.ErrMapEmpty) {
+			exitWithErr(fmt.Errorf("peek into empty %s: %v", m.Name(), err))
+		}
+		for i := uint32(1); i <= 4; i++ {
+			if err = push(m, i, bpf.MapFlagUpdateAny); err != nil {
+				exitWithErr(err)
+			}
+		}
+		if err = push(m, 5, bpf.MapFlagUpdateAny); !errors.Is(err, bpf.ErrMapFull) {
+			exitWithErr(fmt.Errorf("push to full %s: %v", m.Name(), err))
+		}
+		// drops the oldest value, 1
+		if err = push(m, 5, bpf.MapFlagUpdateExist); err != nil {
+			exitWithErr(err)
+		}
+	}
+
+	// first in, first out: 2, 3, 4, 5
+	expect(queue, false, 2)
+	for i := uint32(2); i <= 5; i++ {
+		expect(queue, true, i)
+	}
+
+	// last in, first out: 5, 4, 3, 2
+	expect(stack, false, 5)
+	for i := uint32(5); i >= 2; i-- {
+		expect(stack, true, i)
+	}
+}
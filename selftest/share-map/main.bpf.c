@@ -0,0 +1,21 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __type(key, u32);
+    __type(value, u32);
+    __uint(max_entries, 1);
+} shared SEC(".maps");
+
+SEC("syscall")
+int write_value(u32 *value)
+{
+    u32 zero = 0;
+
+    bpf_map_update_elem(&shared, &zero, value, BPF_ANY);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,115 @@
+package main
+
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	writer, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer writer.Close()
+
+	if err = writer.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	reader, err := bpf.NewModuleFromFile("reader.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer reader.Close()
+
+	if err = reader.ShareMapFrom(writer, "shared"); err != nil {
+		exitWithErr(err)
+	}
+	if err = reader.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	writerMap, err := writer.GetMap("shared")
+	if err != nil {
+		exitWithErr(err)
+	}
+	readerMap, err := reader.GetMap("shared")
+	if err != nil {
+		exitWithErr(err)
+	}
+	writerInfo, err := writerMap.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	readerInfo, err := readerMap.Info()
+	if err != nil {
+		exitWithErr(err)
+	}
+	if writerInfo.ID != readerInfo.ID {
+		exitWithErr(fmt.Errorf("map not shared: ids %d and %d", writerInfo.ID, readerInfo.ID))
+	}
+
+	// the first object writes, the second one reads
+	writeProg, err := writer.GetProgram("write_value")
+	if err != nil {
+		exitWithErr(err)
+	}
+	readProg, err := reader.GetProgram("read_value")
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	value := uint32(42)
+	if _, err = writeProg.RunWithCtx(&value); err != nil {
+		exitWithErr(err)
+	}
+	retval, _, err := readProg.Run(nil)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if retval != value {
+		exitWithErr(fmt.Errorf("second object read %d, expected %d", retval, value))
+	}
+
+	// sharing after load is refused
+	if err = reader.ShareMapFrom(writer, "shared"); err == nil {
+		exitWithErr(fmt.Errorf("sharing a map with a loaded module succeeded"))
+	}
+
+	// ReuseFD is the building block of ShareMapFrom
+	third, err := bpf.NewModuleFromFile("reader.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer third.Close()
+
+	thirdMap, err := third.GetMap("shared")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if err = thirdMap.ReuseFD(writerMap.GetFd()); err != nil {
+		exitWithErr(err)
+	}
+	if err = third.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	key := uint32(0)
+	got, err := thirdMap.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		exitWithErr(err)
+	}
+	if *(*uint32)(unsafe.Pointer(&got[0])) != value {
+		exitWithErr(fmt.Errorf("third object read %v, expected %d", got, value))
+	}
+}
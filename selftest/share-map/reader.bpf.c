@@ -0,0 +1,24 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+// bound to the map of the first object with ShareMapFrom
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __type(key, u32);
+    __type(value, u32);
+    __uint(max_entries, 1);
+} shared SEC(".maps");
+
+SEC("syscall")
+int read_value(void *ctx)
+{
+    u32 zero = 0, *value;
+
+    value = bpf_map_lookup_elem(&shared, &zero);
+    if (!value)
+        return -1;
+    return *value;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,29 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct value {
+    u32 pid;
+    u64 count;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, struct value);
+    __uint(max_entries, 1024);
+} values SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 key = 0;
+    struct value *v;
+
+    v = bpf_map_lookup_elem(&values, &key);
+    if (v)
+        __sync_fetch_and_add(&v->count, 1);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
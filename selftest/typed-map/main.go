@@ -0,0 +1,107 @@
+package main
+
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+// laid out like struct value, 4 bytes of padding included
+type value struct {
+	Pid   uint32
+	Count uint64
+}
+
+func exitWithErr(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(-1)
+}
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFile("main.bpf.o")
+	if err != nil {
+		exitWithErr(err)
+	}
+	defer bpfModule.Close()
+
+	if err = bpfModule.BPFLoadObject(); err != nil {
+		exitWithErr(err)
+	}
+
+	bpfMap, err := bpfModule.GetMap("values")
+	if err != nil {
+		exitWithErr(err)
+	}
+	if _, err = bpf.NewTypedMap[uint64, value](bpfMap); err == nil {
+		exitWithErr(fmt.Errorf("key of the wrong size accepted"))
+	}
+	values, err := bpf.NewTypedMap[uint32, value](bpfMap)
+	if err != nil {
+		exitWithErr(err)
+	}
+
+	// single elements
+	if err = values.Update(1, value{Pid: 100, Count: 1}); err != nil {
+		exitWithErr(err)
+	}
+	if err = values.UpdateFlags(1, value{Pid: 100, Count: 2}, bpf.MapFlagUpdateNoExist); !errors.Is(err, syscall.EEXIST) {
+		exitWithErr(fmt.Errorf("updating an existing element with MapFlagUpdateNoExist: %v", err))
+	}
+	v, err := values.Lookup(1)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if v != (value{Pid: 100, Count: 1}) {
+		exitWithErr(fmt.Errorf("unexpected value %+v", v))
+	}
+
+	// batches
+	keys := []uint32{2, 3, 4}
+	vals := []value{{Pid: 200, Count: 2}, {Pid: 300, Count: 3}, {Pid: 400, Count: 4}}
+	if err = values.UpdateBatch(keys, vals); err != nil {
+		exitWithErr(err)
+	}
+
+	var cursor bpf.BatchCursor
+	found := make(map[uint32]value)
+	for !cursor.Done() {
+		batchKeys, batchValues, err := values.LookupBatch(&cursor, 3)
+		if err != nil {
+			exitWithErr(err)
+		}
+		for i := range batchKeys {
+			found[batchKeys[i]] = batchValues[i]
+		}
+	}
+	if len(found) != 4 || found[3] != vals[1] {
+		exitWithErr(fmt.Errorf("batch lookup found %v", found))
+	}
+
+	if err = values.DeleteBatch([]uint32{2, 3}); err != nil {
+		exitWithErr(err)
+	}
+	if err = values.Delete(4); err != nil {
+		exitWithErr(err)
+	}
+
+	// iteration
+	count := 0
+	it := values.Iterator()
+	for it.Next() {
+		if it.Key() != 1 || it.Value().Pid != 100 {
+			exitWithErr(fmt.Errorf("unexpected element %d: %+v", it.Key(), it.Value()))
+		}
+		count++
+	}
+	if it.Err() != nil {
+		exitWithErr(it.Err())
+	}
+	if count != 1 {
+		exitWithErr(fmt.Errorf("iterated over %d elements, expected 1", count))
+	}
+}
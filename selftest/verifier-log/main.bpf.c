@@ -0,0 +1,22 @@
+//+build ignore
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32);
+    __type(value, u32);
+    __uint(max_entries, 1);
+} tester SEC(".maps");
+
+SEC("kprobe/sys_mmap")
+int kprobe__sys_mmap(struct pt_regs *ctx)
+{
+    u32 key = 0;
+    u32 *value = bpf_map_lookup_elem(&tester, &key);
+
+    // missing NULL check: the verifier must reject this
+    return *value;
+}
+
+char LICENSE[] SEC("license") = "Dual BSD/GPL";
@@ -0,0 +1,53 @@
+package main
+
+import "C"
+
+import (
+	"errors"
+	"os"
+
+	"fmt"
+
+	bpf "github.com/aquasecurity/libbpfgo"
+)
+
+func main() {
+	bpfModule, err := bpf.NewModuleFromFileArgs(bpf.NewModuleArgs{
+		BPFObjPath:    "main.bpf.o",
+		KernelLogSize: 1 << 20,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	defer bpfModule.Close()
+
+	err = bpfModule.BPFLoadObject()
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "undetected error, invalid program loaded")
+		os.Exit(-1)
+	}
+
+	var loadErr *bpf.LoadError
+	if !errors.As(err, &loadErr) {
+		fmt.Fprintf(os.Stderr, "unexpected error type %T, expected *LoadError\n", err)
+		os.Exit(-1)
+	}
+	if loadErr.ProgName != "kprobe__sys_mmap" {
+		fmt.Fprintf(os.Stderr, "wrong failing program: %q\n", loadErr.ProgName)
+		os.Exit(-1)
+	}
+	if loadErr.Errno == 0 {
+		fmt.Fprintln(os.Stderr, "missing errno")
+		os.Exit(-1)
+	}
+
+	log := loadErr.VerifierLog()
+	insn := log.Rejected()
+	if insn == nil || log.Message == "" {
+		fmt.Fprintf(os.Stderr, "could not parse verifier log:\n%s\n", loadErr.Log)
+		os.Exit(-1)
+	}
+
+	fmt.Printf("rejected %d: %s (%s)\n", insn.Index, insn.Text, log.Message)
+}
@@ -0,0 +1,209 @@
+package libbpfgo
+
+/*
+#include <unistd.h>
+
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type BPFStatsType uint32
+
+const (
+	BPFStatsRunTime BPFStatsType = C.BPF_STATS_RUN_TIME // run_cnt and run_time_ns
+)
+
+func (t BPFStatsType) String() string {
+	x := map[BPFStatsType]string{
+		BPFStatsRunTime: "BPF_STATS_RUN_TIME",
+	}
+	str, ok := x[t]
+	if !ok {
+		str = fmt.Sprintf("BPF_STATS_UNKNOWN(%d)", uint32(t))
+	}
+	return str
+}
+
+// StatsGuard keeps kernel BPF stats enabled until it is closed
+type StatsGuard struct {
+	fd     C.int
+	closed bool
+}
+
+// EnableStats turns kernel BPF stats on with BPF_ENABLE_STATS. Stats stay
+// on as long as the returned guard (or any other holder, e.g. the
+// kernel.bpf_stats_enabled sysctl) is open. It requires CAP_SYS_ADMIN.
+func EnableStats(statsType BPFStatsType) (*StatsGuard, error) {
+	fd := C.bpf_enable_stats(uint32(statsType))
+	if fd < 0 {
+		return nil, fmt.Errorf("failed to enable %s stats: %w", statsType, syscall.Errno(-fd))
+	}
+	return &StatsGuard{fd: fd}, nil
+}
+
+// Close turns stats off, unless something else keeps them on
+func (g *StatsGuard) Close() error {
+	if g.closed {
+		return nil
+	}
+	if ret, errno := C.close(g.fd); ret < 0 {
+		return fmt.Errorf("failed to disable stats: %w", errno)
+	}
+	g.closed = true
+	return nil
+}
+
+// ProgStats is the activity of a program over the last sampling interval
+type ProgStats struct {
+	Name       string
+	ID         uint32
+	Interval   time.Duration
+	RunCnt     uint64        // runs during the interval
+	RunTime    time.Duration // time spent running during the interval
+	NsPerRun   float64
+	RunsPerSec float64
+
+	TotalRunCnt  uint64 // since the program was loaded, while stats were on
+	TotalRunTime time.Duration
+}
+
+// StatsCollector samples run_cnt and run_time_ns of the programs of a
+// module on an interval. Stats must be enabled (see EnableStats),
+// otherwise the counters do not move.
+type StatsCollector struct {
+	module    *Module
+	interval  time.Duration
+	statsChan chan []ProgStats
+	stop      chan struct{}
+	stopped   bool // statsChan is closed
+	mu        sync.Mutex
+	wg        sync.WaitGroup
+}
+
+// InitStatsCollector creates a collector sending the stats of all the
+// loaded programs of the module to statsChan, every interval
+func (m *Module) InitStatsCollector(statsChan chan []ProgStats, interval time.Duration) (*StatsCollector, error) {
+	if statsChan == nil {
+		return nil, fmt.Errorf("stats channel can not be nil")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid stats interval %s", interval)
+	}
+
+	return &StatsCollector{
+		module:    m,
+		interval:  interval,
+		statsChan: statsChan,
+	}, nil
+}
+
+// Start starts sampling. It does nothing if the collector is already
+// running, or was stopped: a stopped collector can not be restarted.
+func (sc *StatsCollector) Start() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.stop != nil || sc.stopped {
+		return
+	}
+	sc.stop = make(chan struct{})
+	sc.wg.Add(1)
+	go sc.poll()
+}
+
+// Stop stops sampling, if started, and closes the stats channel. Calling
+// it again does nothing.
+func (sc *StatsCollector) Stop() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.stopped {
+		return
+	}
+	if sc.stop != nil {
+		close(sc.stop)
+		sc.wg.Wait()
+		sc.stop = nil
+	}
+	close(sc.statsChan)
+	sc.stopped = true
+}
+
+func (sc *StatsCollector) poll() {
+	defer sc.wg.Done()
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	prev, prevTime := sc.sample()
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case <-ticker.C:
+		}
+
+		cur, now := sc.sample()
+		interval := now.Sub(prevTime)
+
+		var stats []ProgStats
+		for name, info := range cur {
+			if p, ok := prev[name]; ok && p.ID == info.ID {
+				stats = append(stats, progStatsDelta(name, p, info, interval))
+			}
+		}
+		sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+		prev, prevTime = cur, now
+
+		select {
+		case sc.statsChan <- stats:
+		case <-sc.stop:
+			return
+		}
+	}
+}
+
+// sample returns the info of the loaded programs of the module, by name
+func (sc *StatsCollector) sample() (map[string]*BPFProgInfo, time.Time) {
+	infos := make(map[string]*BPFProgInfo)
+
+	it := sc.module.Iterator()
+	for prog := it.NextProgram(); prog != nil; prog = it.NextProgram() {
+		if prog.GetFd() < 0 {
+			continue
+		}
+		info, err := prog.Info()
+		if err != nil {
+			continue
+		}
+		infos[prog.GetName()] = info
+	}
+	return infos, time.Now()
+}
+
+func progStatsDelta(name string, prev, cur *BPFProgInfo, interval time.Duration) ProgStats {
+	stats := ProgStats{
+		Name:         name,
+		ID:           cur.ID,
+		Interval:     interval,
+		TotalRunCnt:  cur.RunCnt,
+		TotalRunTime: cur.RunTime,
+	}
+	stats.RunCnt = cur.RunCnt - prev.RunCnt
+	stats.RunTime = cur.RunTime - prev.RunTime
+	if stats.RunCnt > 0 {
+		stats.NsPerRun = float64(stats.RunTime.Nanoseconds()) / float64(stats.RunCnt)
+	}
+	if interval > 0 {
+		stats.RunsPerSec = float64(stats.RunCnt) / interval.Seconds()
+	}
+	return stats
+}
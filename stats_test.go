@@ -0,0 +1,52 @@
+package libbpfgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgStatsDelta(t *testing.T) {
+	prev := &BPFProgInfo{ID: 7, RunCnt: 100, RunTime: 10 * time.Microsecond}
+	cur := &BPFProgInfo{ID: 7, RunCnt: 300, RunTime: 50 * time.Microsecond}
+
+	stats := progStatsDelta("prog", prev, cur, 2*time.Second)
+
+	if stats.Name != "prog" || stats.ID != 7 {
+		t.Errorf("unexpected program %s (%d)", stats.Name, stats.ID)
+	}
+	if stats.RunCnt != 200 || stats.RunTime != 40*time.Microsecond {
+		t.Errorf("expected 200 runs in 40µs, got %d runs in %s", stats.RunCnt, stats.RunTime)
+	}
+	if stats.NsPerRun != 200 {
+		t.Errorf("expected 200 ns per run, got %f", stats.NsPerRun)
+	}
+	if stats.RunsPerSec != 100 {
+		t.Errorf("expected 100 runs per second, got %f", stats.RunsPerSec)
+	}
+	if stats.TotalRunCnt != 300 || stats.TotalRunTime != 50*time.Microsecond {
+		t.Errorf("unexpected totals %d, %s", stats.TotalRunCnt, stats.TotalRunTime)
+	}
+
+	idle := progStatsDelta("prog", cur, cur, time.Second)
+	if idle.RunCnt != 0 || idle.NsPerRun != 0 || idle.RunsPerSec != 0 {
+		t.Errorf("unexpected stats for an idle program: %+v", idle)
+	}
+}
+
+func TestStatsCollectorStopWithoutStart(t *testing.T) {
+	statsChan := make(chan []ProgStats)
+	sc, err := (&Module{}).InitStatsCollector(statsChan, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.Stop()
+	if _, ok := <-statsChan; ok {
+		t.Error("expected the stats channel to be closed")
+	}
+	sc.Stop()
+	sc.Start() // stopped collectors can not be restarted
+	if sc.stop != nil {
+		t.Error("expected a stopped collector not to start again")
+	}
+}
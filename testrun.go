@@ -0,0 +1,169 @@
+package libbpfgo
+
+/*
+#include <stdlib.h>
+
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+type TestRunFlag uint32
+
+const (
+	TestRunFlagOnCPU         TestRunFlag = 1 << iota // BPF_F_TEST_RUN_ON_CPU: run on TestRunOpts.CPU (raw_tracepoint)
+	TestRunFlagXDPLiveFrames                         // BPF_F_TEST_XDP_LIVE_FRAMES: send XDP frames for real
+)
+
+// testRunDataOutRoom is the room left by default in the output buffer for
+// programs growing the packet (e.g. bpf_xdp_adjust_tail)
+const testRunDataOutRoom = 4096
+
+// TestRunOpts are the input of a BPF_PROG_TEST_RUN
+type TestRunOpts struct {
+	DataIn []byte // packet, for networking programs
+	CtxIn  []byte // context, e.g. struct xdp_md or struct __sk_buff
+
+	// DataOutSize and CtxOutSize are the sizes of the buffers receiving the
+	// output packet and context. DataOutSize defaults to len(DataIn) plus
+	// 4096 bytes and CtxOutSize to len(CtxIn). If the output does not fit,
+	// TestRun fails with ENOSPC.
+	DataOutSize uint32
+	CtxOutSize  uint32
+
+	Repeat int // number of runs, 0 and 1 both run once
+	Flags  TestRunFlag
+	CPU    uint32 // with TestRunFlagOnCPU
+}
+
+// TestRunResult is the output of a BPF_PROG_TEST_RUN
+type TestRunResult struct {
+	Retval   uint32
+	DataOut  []byte
+	CtxOut   []byte
+	Duration time.Duration // average duration of a run
+}
+
+// TestRun runs the program in the kernel with BPF_PROG_TEST_RUN, without
+// attaching it. Only some program types support it: XDP, TC, socket
+// filters, raw tracepoints, syscall programs, ...
+func (p *BPFProg) TestRun(opts TestRunOpts) (*TestRunResult, error) {
+	dataOutSize := opts.DataOutSize
+	if dataOutSize == 0 && len(opts.DataIn) > 0 {
+		dataOutSize = uint32(len(opts.DataIn)) + testRunDataOutRoom
+	}
+	ctxOutSize := opts.CtxOutSize
+	if ctxOutSize == 0 {
+		ctxOutSize = uint32(len(opts.CtxIn))
+	}
+
+	// buffers are allocated in C, as the kernel reads and writes them
+	// through the options struct
+	cOpts := C.struct_bpf_test_run_opts{
+		sz:            C.sizeof_struct_bpf_test_run_opts,
+		data_size_in:  C.uint(len(opts.DataIn)),
+		data_size_out: C.uint(dataOutSize),
+		ctx_size_in:   C.uint(len(opts.CtxIn)),
+		ctx_size_out:  C.uint(ctxOutSize),
+		repeat:        C.int(opts.Repeat),
+		flags:         C.uint(opts.Flags),
+		cpu:           C.uint(opts.CPU),
+	}
+	if len(opts.DataIn) > 0 {
+		cOpts.data_in = C.CBytes(opts.DataIn)
+		defer C.free(cOpts.data_in)
+	}
+	if dataOutSize > 0 {
+		cOpts.data_out = C.malloc(C.size_t(dataOutSize))
+		defer C.free(cOpts.data_out)
+	}
+	if len(opts.CtxIn) > 0 {
+		cOpts.ctx_in = C.CBytes(opts.CtxIn)
+		defer C.free(cOpts.ctx_in)
+	}
+	if ctxOutSize > 0 {
+		cOpts.ctx_out = C.malloc(C.size_t(ctxOutSize))
+		defer C.free(cOpts.ctx_out)
+	}
+
+	errC := C.bpf_prog_test_run_opts(C.int(p.GetFd()), &cOpts)
+	if errC < 0 {
+		return nil, fmt.Errorf("failed to test run program %s: %w", p.name, syscall.Errno(-errC))
+	}
+
+	result := &TestRunResult{
+		Retval:   uint32(cOpts.retval),
+		Duration: time.Duration(cOpts.duration),
+	}
+	if cOpts.data_out != nil {
+		result.DataOut = C.GoBytes(cOpts.data_out, C.int(cOpts.data_size_out))
+	}
+	if cOpts.ctx_out != nil && cOpts.ctx_size_out > 0 {
+		result.CtxOut = C.GoBytes(cOpts.ctx_out, C.int(cOpts.ctx_size_out))
+	}
+
+	return result, nil
+}
+
+// Run runs a BPF_PROG_TYPE_SYSCALL program with ctx as its context. It
+// returns the return value of the program and the context as the program
+// left it.
+func (p *BPFProg) Run(ctx []byte) (uint32, []byte, error) {
+	if p.GetType() != BPFProgTypeSyscall {
+		return 0, nil, fmt.Errorf("failed to run program %s: %s is not %s: %w", p.name, p.GetType(), BPFProgTypeSyscall, syscall.EINVAL)
+	}
+
+	// syscall programs take no packet nor output context: the kernel
+	// writes the context back to the input buffer
+	cOpts := C.struct_bpf_test_run_opts{
+		sz:          C.sizeof_struct_bpf_test_run_opts,
+		ctx_size_in: C.uint(len(ctx)),
+	}
+	if len(ctx) > 0 {
+		cOpts.ctx_in = C.CBytes(ctx)
+		defer C.free(cOpts.ctx_in)
+	}
+
+	errC := C.bpf_prog_test_run_opts(C.int(p.GetFd()), &cOpts)
+	if errC < 0 {
+		return 0, nil, fmt.Errorf("failed to run program %s: %w", p.name, syscall.Errno(-errC))
+	}
+
+	var ctxOut []byte
+	if len(ctx) > 0 {
+		ctxOut = C.GoBytes(cOpts.ctx_in, C.int(len(ctx)))
+	}
+	return uint32(cOpts.retval), ctxOut, nil
+}
+
+// RunWithCtx is like Run, with ctx a pointer to a fixed-size value (see
+// encoding/binary) laid out like the C context of the program, padding
+// included. ctx is updated with the context the program left.
+func (p *BPFProg) RunWithCtx(ctx interface{}) (uint32, error) {
+	if reflect.ValueOf(ctx).Kind() != reflect.Ptr || binary.Size(ctx) < 0 {
+		return 0, fmt.Errorf("failed to run program %s: %T is not a pointer to a fixed-size value: %w", p.name, ctx, syscall.EINVAL)
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, nativeEndian, ctx); err != nil {
+		return 0, fmt.Errorf("failed to encode context of program %s: %w", p.name, err)
+	}
+
+	retval, ctxOut, err := p.Run(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	if err = binary.Read(bytes.NewReader(ctxOut), nativeEndian, ctx); err != nil {
+		return 0, fmt.Errorf("failed to decode context of program %s: %w", p.name, err)
+	}
+	return retval, nil
+}
@@ -0,0 +1,218 @@
+package libbpfgo
+
+/*
+#include <bpf/bpf.h>
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// TypedMap wraps a BPFMap with Go types for its keys and values. Keys and
+// values are copied as is, in native endianness: K and V must be
+// fixed-size types (see encoding/binary) laid out like their C
+// counterpart. Go aligns fields as C does, so
+//
+//	struct value {        type value struct {
+//	    u32 pid;              Pid   uint32
+//	    u64 count;            Count uint64
+//	};                    }
+//
+// both have 4 bytes of padding after pid and a size of 16 bytes.
+//
+// Per-CPU maps are not supported, their values depending on the number of
+// CPUs.
+type TypedMap[K, V any] struct {
+	bpfMap *BPFMap
+}
+
+// NewTypedMap wraps bpfMap, checking that the sizes of K and V match the
+// key and value sizes of the map
+func NewTypedMap[K, V any](bpfMap *BPFMap) (*TypedMap[K, V], error) {
+	var key K
+	var value V
+
+	if binary.Size(key) < 0 {
+		return nil, fmt.Errorf("key type %T of map %s is not fixed-size", key, bpfMap.name)
+	}
+	if binary.Size(value) < 0 {
+		return nil, fmt.Errorf("value type %T of map %s is not fixed-size", value, bpfMap.name)
+	}
+	if size := int(unsafe.Sizeof(key)); size != bpfMap.KeySize() {
+		return nil, fmt.Errorf("key type %T of map %s has size %d, expected %d", key, bpfMap.name, size, bpfMap.KeySize())
+	}
+	if size := int(unsafe.Sizeof(value)); size != bpfMap.ValueSize() {
+		return nil, fmt.Errorf("value type %T of map %s has size %d, expected %d", value, bpfMap.name, size, bpfMap.ValueSize())
+	}
+	if isPerCPU(bpfMap.Type()) {
+		return nil, fmt.Errorf("map %s is a per-CPU map: %s", bpfMap.name, bpfMap.Type())
+	}
+
+	return &TypedMap[K, V]{bpfMap: bpfMap}, nil
+}
+
+// Map returns the underlying map
+func (m *TypedMap[K, V]) Map() *BPFMap {
+	return m.bpfMap
+}
+
+func (m *TypedMap[K, V]) Lookup(key K) (V, error) {
+	var value V
+
+	raw, err := m.bpfMap.GetValue(unsafe.Pointer(&key))
+	if err != nil {
+		return value, err
+	}
+	copy(bytesOf(&value), raw)
+	return value, nil
+}
+
+func (m *TypedMap[K, V]) Update(key K, value V) error {
+	return m.UpdateFlags(key, value, MapFlagUpdateAny)
+}
+
+func (m *TypedMap[K, V]) UpdateFlags(key K, value V, flags MapFlag) error {
+	return m.bpfMap.UpdateValueFlags(unsafe.Pointer(&key), unsafe.Pointer(&value), flags)
+}
+
+func (m *TypedMap[K, V]) Delete(key K) error {
+	return m.bpfMap.DeleteKey(unsafe.Pointer(&key))
+}
+
+// BatchCursor keeps track of a batch lookup across calls to LookupBatch.
+// Its zero value starts from the beginning of the map.
+type BatchCursor struct {
+	started bool
+	done    bool
+	token   []byte
+}
+
+// Done tells whether the whole map was looked up
+func (c *BatchCursor) Done() bool {
+	return c.done
+}
+
+// LookupBatch looks up to count elements of the map, after those returned
+// by the previous calls with the same cursor. It returns fewer elements
+// once the end of the map is reached, at which point cursor.Done returns
+// true.
+func (m *TypedMap[K, V]) LookupBatch(cursor *BatchCursor, count uint32) ([]K, []V, error) {
+	if cursor.done || count == 0 {
+		return nil, nil, nil
+	}
+
+	var startKey unsafe.Pointer
+	if cursor.started {
+		startKey = unsafe.Pointer(&cursor.token[0])
+	}
+	nextKey := make([]byte, m.bpfMap.batchTokenSize())
+
+	opts := &BPFMapBatchOpts{
+		Sz:        uint64(unsafe.Sizeof(BPFMapBatchOpts{})),
+		ElemFlags: C.BPF_ANY,
+		Flags:     C.BPF_ANY,
+	}
+
+	keys := make([]K, count)
+	values := make([]V, count)
+	countC := C.uint(count)
+
+	var err error
+	errC := C.bpf_map_lookup_batch(m.bpfMap.fd, startKey, unsafe.Pointer(&nextKey[0]),
+		unsafe.Pointer(&keys[0]), unsafe.Pointer(&values[0]), &countC, bpfMapBatchOptsToC(opts))
+	if errC != 0 {
+		err = syscall.Errno(-errC)
+	}
+
+	n, err := cursor.advance(nextKey, uint32(countC), err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to batch lookup map %s: %w", m.bpfMap.name, err)
+	}
+	return keys[:n], values[:n], nil
+}
+
+// advance moves the cursor past a batch of n entries, looked up with err.
+// ENOENT ends the map, the kernel still returning its last entries.
+func (c *BatchCursor) advance(token []byte, n uint32, err error) (uint32, error) {
+	if err != nil && !errors.Is(err, syscall.ENOENT) {
+		return 0, err
+	}
+	c.done = err != nil
+	c.started = true
+	c.token = token
+	return n, nil
+}
+
+// UpdateBatch updates the elements of keys with values, of the same length
+func (m *TypedMap[K, V]) UpdateBatch(keys []K, values []V) error {
+	if len(keys) != len(values) {
+		return fmt.Errorf("failed to batch update map %s: %d keys for %d values", m.bpfMap.name, len(keys), len(values))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return m.bpfMap.UpdateBatch(unsafe.Pointer(&keys[0]), unsafe.Pointer(&values[0]), uint32(len(keys)))
+}
+
+func (m *TypedMap[K, V]) DeleteBatch(keys []K) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return m.bpfMap.DeleteKeyBatch(unsafe.Pointer(&keys[0]), uint32(len(keys)))
+}
+
+// TypedMapIterator iterates over the elements of a TypedMap, see
+// BPFMapEntryIterator for what happens to elements deleted while iterating
+type TypedMapIterator[K, V any] struct {
+	it    *BPFMapEntryIterator
+	key   K
+	value V
+}
+
+// Iterator returns an iterator reading DefaultEntryChunkSize elements at
+// a time
+func (m *TypedMap[K, V]) Iterator() *TypedMapIterator[K, V] {
+	return m.ChunkedIterator(0)
+}
+
+// ChunkedIterator returns an iterator reading chunkSize elements at a time
+func (m *TypedMap[K, V]) ChunkedIterator(chunkSize uint32) *TypedMapIterator[K, V] {
+	return &TypedMapIterator[K, V]{
+		it: m.bpfMap.EntryIterator(chunkSize),
+	}
+}
+
+func (it *TypedMapIterator[K, V]) Next() bool {
+	if !it.it.Next() {
+		return false
+	}
+	copy(bytesOf(&it.key), it.it.Key())
+	copy(bytesOf(&it.value), it.it.Value())
+	return true
+}
+
+// Key returns the current key, if the most recent call to Next returned true
+func (it *TypedMapIterator[K, V]) Key() K {
+	return it.key
+}
+
+// Value returns the current value, if the most recent call to Next
+// returned true
+func (it *TypedMapIterator[K, V]) Value() V {
+	return it.value
+}
+
+// Err returns the last error that occurred while iterating
+func (it *TypedMapIterator[K, V]) Err() error {
+	return it.it.Err()
+}
+
+// bytesOf returns the memory of *v
+func bytesOf[T any](v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), unsafe.Sizeof(*v))
+}
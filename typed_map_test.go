@@ -0,0 +1,79 @@
+package libbpfgo
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestNewTypedMap(t *testing.T) {
+	type value struct {
+		Pid   uint32
+		Count uint64
+	}
+	hash := &BPFMap{name: "hash", info: &BPFMapInfo{Type: MapTypeHash, KeySize: 4, ValueSize: 16}}
+
+	if _, err := NewTypedMap[uint32, value](hash); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewTypedMap[uint64, value](hash); err == nil {
+		t.Error("expected an error for a key of the wrong size")
+	}
+	if _, err := NewTypedMap[uint32, [2]uint32](hash); err == nil {
+		t.Error("expected an error for a value of the wrong size")
+	}
+	if _, err := NewTypedMap[uint32, [2]*uint64](hash); err == nil {
+		t.Error("expected an error for a value with pointers")
+	}
+
+	percpu := &BPFMap{name: "percpu", info: &BPFMapInfo{Type: MapTypePerCPUArray, KeySize: 4, ValueSize: 16}}
+	if _, err := NewTypedMap[uint32, value](percpu); err == nil {
+		t.Error("expected an error for a per-CPU map")
+	}
+}
+
+func TestBytesOf(t *testing.T) {
+	v := struct {
+		A uint8
+		B uint32
+	}{A: 1, B: 2}
+
+	b := bytesOf(&v)
+	if len(b) != 8 {
+		t.Fatalf("expected 8 bytes, got %d", len(b))
+	}
+	if b[0] != 1 || nativeEndian.Uint32(b[4:]) != 2 {
+		t.Errorf("unexpected bytes %v", b)
+	}
+
+	b[0] = 3
+	if v.A != 3 {
+		t.Errorf("bytes do not alias the value")
+	}
+}
+
+func TestBatchCursorAdvance(t *testing.T) {
+	var cursor BatchCursor
+
+	n, err := cursor.advance([]byte{1, 0, 0, 0}, 4, nil)
+	if err != nil || n != 4 || cursor.Done() {
+		t.Fatalf("full batch: got %d, %v, done %v", n, err, cursor.Done())
+	}
+
+	// the kernel returns the last entries along with ENOENT
+	n, err = cursor.advance([]byte{2, 0, 0, 0}, 3, syscall.ENOENT)
+	if err != nil || n != 3 || !cursor.Done() {
+		t.Fatalf("final batch: got %d, %v, done %v", n, err, cursor.Done())
+	}
+	if cursor.token[0] != 2 {
+		t.Errorf("cursor token not advanced: %v", cursor.token)
+	}
+
+	cursor = BatchCursor{}
+	if _, err = cursor.advance(nil, 2, syscall.EFAULT); !errors.Is(err, syscall.EFAULT) {
+		t.Errorf("expected EFAULT, got %v", err)
+	}
+	if cursor.Done() {
+		t.Error("cursor done after an error")
+	}
+}
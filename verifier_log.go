@@ -0,0 +1,217 @@
+package libbpfgo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LoadError is returned by BPFLoadObject when libbpf fails to load the
+// BPF object. If the failure happened while loading a program, ProgName
+// holds the name of the rejected program and Log its full verifier log.
+type LoadError struct {
+	ProgName string
+	Errno    syscall.Errno
+	Log      string
+}
+
+func (e *LoadError) Error() string {
+	if e.ProgName == "" {
+		return fmt.Sprintf("failed to load BPF object: %v", e.Errno)
+	}
+	return fmt.Sprintf("failed to load BPF object: program %s: %v", e.ProgName, e.Errno)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Errno
+}
+
+// VerifierLog parses the verifier log of the rejected program
+func (e *LoadError) VerifierLog() *VerifierLog {
+	return ParseVerifierLog(e.Log)
+}
+
+// VerifierInsn is a single instruction, as printed by the kernel verifier
+type VerifierInsn struct {
+	Index  int    // instruction index within the program
+	Opcode uint8  // raw BPF opcode
+	Text   string // disassembled instruction, e.g. "r0 = *(u32 *)(r1 +0)"
+
+	// Registers holds the register and stack slot state reported by the
+	// verifier for this instruction (e.g. "R1" => "ctx(off=0,imm=0)"),
+	// or nil if the verifier did not print it.
+	Registers map[string]string
+
+	// Source is the source line hint (BTF line info) that preceded the
+	// instruction. SourceFile and SourceLine are only set when the kernel
+	// includes the "@ file:line" location in the hint.
+	Source     string
+	SourceFile string
+	SourceLine int
+}
+
+// VerifierLog is the parsed form of a kernel verifier log. Instructions
+// are kept in the order the verifier walked them, so the same instruction
+// index can show up more than once (one per explored path).
+type VerifierLog struct {
+	Insns   []VerifierInsn
+	Message string // why the program was rejected, empty on success
+	Stats   string // "processed N insns ..." summary, when present
+}
+
+// Rejected returns the last instruction the verifier looked at, which is
+// the one it rejected when the load failed, or nil if there is none.
+func (v *VerifierLog) Rejected() *VerifierInsn {
+	if len(v.Insns) == 0 {
+		return nil
+	}
+	return &v.Insns[len(v.Insns)-1]
+}
+
+var (
+	verifierInsnRegexp   = regexp.MustCompile(`^(\d+): \(([0-9a-f]{2})\) (.*)$`)
+	verifierStateRegexp  = regexp.MustCompile(`^(\d+): ((?:R\d+|fp-?\d+)\S*=.*)$`)
+	verifierBranchRegexp = regexp.MustCompile(`^from \d+ to (\d+): (.*)$`)
+	verifierSourceRegexp = regexp.MustCompile(`^(.*?)\s*@ (\S+):(\d+)$`)
+)
+
+// ParseVerifierLog parses the raw log of the kernel verifier into per
+// instruction entries, with the register state and source line hints
+// attached to the instruction they refer to.
+func ParseVerifierLog(log string) *VerifierLog {
+	v := &VerifierLog{}
+
+	var (
+		source  string // pending source line hint
+		state   map[string]string
+		stateAt = -1 // instruction index the pending state belongs to
+		message []string
+	)
+
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "; "):
+			source = strings.TrimPrefix(line, "; ")
+			continue
+
+		case strings.HasPrefix(line, "processed "):
+			v.Stats = line
+			continue
+		}
+
+		if m := verifierInsnRegexp.FindStringSubmatch(line); m != nil {
+			idx, _ := strconv.Atoi(m[1])
+			opcode, _ := strconv.ParseUint(m[2], 16, 8)
+
+			insn := VerifierInsn{
+				Index:  idx,
+				Opcode: uint8(opcode),
+				Text:   m[3],
+			}
+			// newer kernels append the state after the instruction: "r0 = 0 ; R0_w=0"
+			if text, regs, ok := splitInlineState(m[3]); ok {
+				insn.Text = text
+				insn.Registers = parseVerifierRegs(regs)
+			} else if stateAt == idx {
+				insn.Registers = state
+			}
+			if source != "" {
+				insn.Source = source
+				if s := verifierSourceRegexp.FindStringSubmatch(source); s != nil {
+					insn.Source = s[1]
+					insn.SourceFile = s[2]
+					insn.SourceLine, _ = strconv.Atoi(s[3])
+				}
+			}
+
+			v.Insns = append(v.Insns, insn)
+			source, state, stateAt = "", nil, -1
+			message = message[:0]
+			continue
+		}
+
+		if m := verifierStateRegexp.FindStringSubmatch(line); m != nil {
+			stateAt, _ = strconv.Atoi(m[1])
+			state = parseVerifierRegs(m[2])
+			continue
+		}
+		if m := verifierBranchRegexp.FindStringSubmatch(line); m != nil {
+			stateAt, _ = strconv.Atoi(m[1])
+			state = parseVerifierRegs(m[2])
+			continue
+		}
+
+		// anything else after the last instruction explains the verdict
+		message = append(message, line)
+	}
+
+	v.Message = strings.Join(message, "\n")
+
+	return v
+}
+
+// splitInlineState splits "insn ; R0_w=0 R1=ctx()" into the instruction
+// text and the register state, ignoring separators within parentheses.
+func splitInlineState(s string) (string, string, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';':
+			if depth != 0 || i == 0 || (s[i-1] != ' ' && s[i-1] != '\t') {
+				continue
+			}
+			regs := strings.TrimSpace(s[i+1:])
+			if !strings.HasPrefix(regs, "R") && !strings.HasPrefix(regs, "fp") {
+				continue
+			}
+			return strings.TrimSpace(s[:i]), regs, true
+		}
+	}
+	return s, "", false
+}
+
+// parseVerifierRegs parses "R1=ctx(off=0,imm=0) R10=fp0 fp-8=mmmmmmmm"
+func parseVerifierRegs(s string) map[string]string {
+	regs := make(map[string]string)
+
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch s[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ' ', '\t':
+				if depth == 0 {
+					break
+				}
+				continue
+			default:
+				continue
+			}
+		}
+
+		if tok := s[start:i]; tok != "" {
+			if k := strings.IndexByte(tok, '='); k > 0 {
+				regs[tok[:k]] = tok[k+1:]
+			}
+		}
+		start = i + 1
+	}
+
+	return regs
+}
@@ -0,0 +1,103 @@
+package libbpfgo
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestParseVerifierLog(t *testing.T) {
+	log := `func#0 @0
+0: R1=ctx(off=0,imm=0) R10=fp0
+; int kprobe__sys_mmap(struct pt_regs *ctx) @ main.bpf.c:21
+0: (b7) r6 = 0                        ; R6_w=0
+1: (63) *(u32 *)(r10 -4) = r6         ; R6_w=0 R10=fp0 fp-8=0000????
+; struct value *v1 = bpf_map_lookup_elem(&tester, &firstKey);
+2: (bf) r2 = r10
+3: (07) r2 += -4
+4: (18) r1 = 0xffff888100e5c000
+6: (85) call bpf_map_lookup_elem#1
+7: R0=map_value_or_null(id=1,off=0,ks=4,vs=8,imm=0) R6=0 R10=fp0
+; return v1->x;
+7: (61) r0 = *(u32 *)(r0 +0)
+R0 invalid mem access 'map_value_or_null'
+processed 7 insns (limit 1000000) max_states_per_insn 0 total_states 0 peak_states 0 mark_read 0
+`
+
+	v := ParseVerifierLog(log)
+
+	if len(v.Insns) != 7 {
+		t.Fatalf("expected 7 instructions, got %d", len(v.Insns))
+	}
+
+	first := v.Insns[0]
+	if first.Index != 0 || first.Opcode != 0xb7 || first.Text != "r6 = 0" {
+		t.Errorf("unexpected first instruction: %+v", first)
+	}
+	if first.Registers["R6_w"] != "0" {
+		t.Errorf("unexpected inline register state: %v", first.Registers)
+	}
+	if first.Source != "int kprobe__sys_mmap(struct pt_regs *ctx)" ||
+		first.SourceFile != "main.bpf.c" || first.SourceLine != 21 {
+		t.Errorf("unexpected source hint: %q %q %d", first.Source, first.SourceFile, first.SourceLine)
+	}
+
+	if regs := v.Insns[1].Registers; regs["fp-8"] != "0000????" || regs["R10"] != "fp0" {
+		t.Errorf("unexpected stack state: %v", regs)
+	}
+
+	rejected := v.Rejected()
+	if rejected == nil {
+		t.Fatalf("no rejected instruction")
+	}
+	if rejected.Index != 7 || rejected.Text != "r0 = *(u32 *)(r0 +0)" {
+		t.Errorf("unexpected rejected instruction: %+v", rejected)
+	}
+	if rejected.Source != "return v1->x;" {
+		t.Errorf("unexpected rejected source hint: %q", rejected.Source)
+	}
+	if r0 := rejected.Registers["R0"]; r0 != "map_value_or_null(id=1,off=0,ks=4,vs=8,imm=0)" {
+		t.Errorf("unexpected R0 state: %q", r0)
+	}
+	if v.Message != "R0 invalid mem access 'map_value_or_null'" {
+		t.Errorf("unexpected message: %q", v.Message)
+	}
+	if v.Stats == "" {
+		t.Errorf("missing stats")
+	}
+}
+
+func TestParseVerifierLogEmpty(t *testing.T) {
+	v := ParseVerifierLog("")
+	if v.Rejected() != nil || v.Message != "" {
+		t.Errorf("unexpected result for empty log: %+v", v)
+	}
+}
+
+func TestLoadErrorUnwrap(t *testing.T) {
+	err := error(&LoadError{ProgName: "foo", Errno: syscall.EACCES})
+
+	if !errors.Is(err, syscall.EACCES) {
+		t.Errorf("LoadError does not unwrap to its errno")
+	}
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.ProgName != "foo" {
+		t.Errorf("errors.As failed on LoadError")
+	}
+}
+
+func TestFirstLogged(t *testing.T) {
+	testCases := []struct {
+		head     []byte
+		expected int
+	}{
+		{[]byte{0, 0, 0}, -1},
+		{[]byte{'f', 'u', 'n'}, 0},
+		{[]byte{0, 0, 'f'}, 2},
+	}
+	for _, tc := range testCases {
+		if i := firstLogged(tc.head); i != tc.expected {
+			t.Errorf("%q: expected program %d, got %d", tc.head, tc.expected, i)
+		}
+	}
+}